@@ -1,34 +1,265 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type Entry[T any] struct {
-	Value      T
+type Entry[K comparable, V any] struct {
+	Key        K
+	Value      V
 	Expiration time.Time
+	// Set on negative entries caching a failed fetch
+	Err error
 }
 
-type Cache[K any, V any] struct {
-	m sync.Map
+// Reports if the entry may still be served while it is refreshed
+func (e *Entry[K, V]) stale(now time.Time) (ok bool) {
+	return e.Err == nil && !now.After(e.Expiration.Add(time.Duration(defaultStale.Load())))
+}
+
+// Cache of expiring entries. The zero value is ready to use, expired entries
+// are removed by Sweep and the least recently used ones once the maximum
+// entries are reached
+type Cache[K comparable, V any] struct {
+	mu         sync.Mutex
+	entries    map[K]*list.Element
+	lru        *list.List
+	flights    map[K]*flight[V]
+	maxEntries int
+	registered bool
+	backend    Backend
+	namespace  string
+	// Increased by Purge so the fetches in progress don't store their values
+	generation uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Counters of a cache
+type Stats struct {
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+func (c *Cache[K, V]) init() {
+	if c.entries != nil {
+		return
+	}
+	c.entries = make(map[K]*list.Element)
+	c.lru = list.New()
+	c.flights = make(map[K]*flight[V])
+}
+
+// SetMaxEntries overrides DefaultMaxEntries for the cache, zero restores the
+// default
+func (c *Cache[K, V]) SetMaxEntries(maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = maxEntries
+	c.evict()
+}
+
+func (c *Cache[K, V]) limit() (maxEntries int) {
+	if c.maxEntries > 0 {
+		return c.maxEntries
+	}
+	return int(defaultMaxEntries.Load())
 }
 
 func (c *Cache[K, V]) Store(key K, value V, timeout time.Duration) {
-	c.m.Store(key, &Entry[V]{Value: value, Expiration: time.Now().Add(timeout)})
+	now := time.Now()
+
+	c.mu.Lock()
+	c.store(&Entry[K, V]{Key: key, Value: value, Expiration: now.Add(timeout)})
+	backend := c.backend
+	c.mu.Unlock()
+
+	c.persist(backend, key, value, now)
+}
+
+func (c *Cache[K, V]) store(entry *Entry[K, V]) {
+	c.init()
+	if !c.registered {
+		c.registered = true
+		register(c)
+	}
+
+	if element, found := c.entries[entry.Key]; found {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[entry.Key] = c.lru.PushFront(entry)
+	c.evict()
 }
 
 func (c *Cache[K, V]) Load(key K) (value V, found bool) {
 	now := time.Now()
 
-	rawEntry, found := c.m.Load(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.init()
+	element, found := c.entries[key]
 	if !found {
+		c.miss()
 		return value, false
 	}
 
-	entry := rawEntry.(*Entry[V])
-	if now.After(entry.Expiration) {
+	entry := element.Value.(*Entry[K, V])
+	if now.After(entry.Expiration) && !entry.stale(now) {
+		c.remove(element)
+		c.miss()
 		return value, false
 	}
+	if now.After(entry.Expiration) || entry.Err != nil {
+		// Stale or negative entries are only served by LoadOrFetch
+		c.miss()
+		return value, false
+	}
+	c.lru.MoveToFront(element)
+	c.hit()
 	return entry.Value, true
 }
+
+// Delete removes the entry of the key
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.init()
+	if element, found := c.entries[key]; found {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+	if c.backend != nil {
+		err := c.backend.Delete(c.backendKey(key))
+		if err != nil {
+			totals.Add("backend-errors", 1)
+		}
+	}
+}
+
+// DeleteFunc removes the entries matching the function, returns their keys
+func (c *Cache[K, V]) DeleteFunc(match func(entry *Entry[K, V]) bool) (keys []K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.init()
+	for key, element := range c.entries {
+		if !match(element.Value.(*Entry[K, V])) {
+			continue
+		}
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		keys = append(keys, key)
+		if c.backend != nil {
+			err := c.backend.Delete(c.backendKey(key))
+			if err != nil {
+				totals.Add("backend-errors", 1)
+			}
+		}
+	}
+	return keys
+}
+
+// Range calls the function with every entry until it returns false. The
+// entries must not be modified
+func (c *Cache[K, V]) Range(fn func(entry *Entry[K, V]) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.init()
+	for _, element := range c.entries {
+		if !fn(element.Value.(*Entry[K, V])) {
+			return
+		}
+	}
+}
+
+// Purge removes every entry, persisted ones included. The fetches in progress
+// still answer their waiters but their values are not cached
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+	c.lru = nil
+	c.flights = nil
+	c.generation++
+	c.init()
+	if c.backend != nil {
+		err := c.backend.Purge(c.namespace)
+		if err != nil {
+			totals.Add("backend-errors", 1)
+		}
+	}
+}
+
+// RemoveExpired evicts the expired entries, returns how many were removed
+func (c *Cache[K, V]) RemoveExpired() (removed int) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.init()
+	for element := c.lru.Back(); element != nil; {
+		previous := element.Prev()
+		entry := element.Value.(*Entry[K, V])
+		if now.After(entry.Expiration) && !entry.stale(now) {
+			c.remove(element)
+			removed++
+		}
+		element = previous
+	}
+	return removed
+}
+
+func (c *Cache[K, V]) Stats() (stats Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   len(c.entries),
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+// Removes the least recently used entries above the limit
+func (c *Cache[K, V]) evict() {
+	maxEntries := c.limit()
+	if maxEntries <= 0 {
+		return
+	}
+	for len(c.entries) > maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache[K, V]) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*Entry[K, V]).Key)
+	c.evictions.Add(1)
+	totals.Add("evictions", 1)
+}
+
+func (c *Cache[K, V]) hit() {
+	c.hits.Add(1)
+	totals.Add("hits", 1)
+}
+
+func (c *Cache[K, V]) miss() {
+	c.misses.Add(1)
+	totals.Add("misses", 1)
+}
@@ -0,0 +1,352 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Backend keeping the entries in memory
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]persisted
+}
+
+type persisted struct {
+	value     any
+	fetchedAt time.Time
+}
+
+func (b *memoryBackend) Get(key string, value any) (fetchedAt time.Time, found bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, found := b.entries[key]
+	if !found {
+		return fetchedAt, false, nil
+	}
+	*(value.(*string)) = stored.value.(string)
+	return stored.fetchedAt, true, nil
+}
+
+func (b *memoryBackend) Put(key string, value any, fetchedAt time.Time) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.entries == nil {
+		b.entries = make(map[string]persisted)
+	}
+	b.entries[key] = persisted{value: value, fetchedAt: fetchedAt}
+	return nil
+}
+
+func (b *memoryBackend) Delete(key string) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, key)
+	return nil
+}
+
+func (b *memoryBackend) Purge(namespace string) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.entries {
+		if strings.HasPrefix(key, namespace+"/") {
+			delete(b.entries, key)
+		}
+	}
+	return nil
+}
+
+func TestCache_Load(t *testing.T) {
+	SetDefaults(Defaults{})
+
+	t.Run("Fresh", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		var c Cache[string, int]
+		c.Store("key", 1, time.Minute)
+
+		value, found := c.Load("key")
+		assertions.True(found, "found")
+		assertions.Equal(1, value, "value")
+	})
+	t.Run("Expired", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		var c Cache[string, int]
+		c.Store("key", 1, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		_, found := c.Load("key")
+		assertions.False(found, "found")
+		assertions.Equal(0, c.Stats().Entries, "entries")
+	})
+	t.Run("Missing", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		var c Cache[string, int]
+		_, found := c.Load("key")
+		assertions.False(found, "found")
+	})
+}
+
+func TestCache_SetMaxEntries(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, int]
+	c.Store("a", 1, time.Minute)
+	c.Store("b", 2, time.Minute)
+	c.Store("c", 3, time.Minute)
+	// Refreshes a, leaving b as the least recently used
+	_, found := c.Load("a")
+	assertions.True(found, "found a")
+
+	c.SetMaxEntries(2)
+
+	_, found = c.Load("b")
+	assertions.False(found, "b evicted")
+	_, found = c.Load("a")
+	assertions.True(found, "a kept")
+	_, found = c.Load("c")
+	assertions.True(found, "c kept")
+
+	c.Store("d", 4, time.Minute)
+	// a was loaded before c, so it is the least recently used now
+	_, found = c.Load("a")
+	assertions.False(found, "a evicted")
+	assertions.Equal(2, c.Stats().Entries, "entries")
+}
+
+func TestCache_RemoveExpired(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, int]
+	c.Store("expired", 1, time.Millisecond)
+	c.Store("fresh", 2, time.Minute)
+	time.Sleep(5 * time.Millisecond)
+
+	assertions.Equal(1, c.RemoveExpired(), "removed")
+	assertions.Equal(1, c.Stats().Entries, "entries")
+	_, found := c.Load("fresh")
+	assertions.True(found, "fresh kept")
+}
+
+func TestCache_RemoveExpiredStale(t *testing.T) {
+	SetDefaults(Defaults{Stale: time.Minute})
+	defer SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, int]
+	c.Store("stale", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	assertions.Equal(0, c.RemoveExpired(), "stale entries are kept")
+}
+
+func TestSweep(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, int]
+	c.Store("expired", 1, time.Millisecond)
+	c.Store("fresh", 2, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Sweep(ctx, time.Millisecond)
+
+	assertions.Eventually(func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.entries) == 1
+	}, time.Second, time.Millisecond, "expired entry swept")
+}
+
+func TestCache_Stats(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, int]
+	c.SetMaxEntries(1)
+	c.Store("a", 1, time.Minute)
+	c.Load("a")
+	c.Load("missing")
+	c.Store("b", 2, time.Minute)
+
+	assertions.Equal(Stats{Entries: 1, Hits: 1, Misses: 1, Evictions: 1}, c.Stats(), "stats")
+}
+
+func TestCache_LoadOrFetchPersisted(t *testing.T) {
+	SetDefaults(Defaults{Stale: time.Minute})
+	defer SetDefaults(Defaults{})
+
+	type Test struct {
+		Name      string
+		FetchedAt time.Time
+		Refreshed bool
+	}
+	tests := []Test{
+		{Name: "Fresh", FetchedAt: time.Now()},
+		{Name: "Stale", FetchedAt: time.Now().Add(-90 * time.Second), Refreshed: true},
+		{Name: "Past the stale period", FetchedAt: time.Now().Add(-24 * time.Hour), Refreshed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			backend := new(memoryBackend)
+			backend.Put("test/key", "persisted", test.FetchedAt)
+			var c Cache[string, string]
+			c.Persist(backend, "test")
+
+			refreshed := make(chan struct{})
+			value, err := c.LoadOrFetch(context.Background(), "key", time.Minute, func(ctx context.Context) (value string, err error) {
+				close(refreshed)
+				return "fetched", nil
+			})
+			if !assertions.Nil(err, "failed to load") {
+				return
+			}
+			// Served while refreshed in the background
+			assertions.Equal("persisted", value, "value")
+
+			if !test.Refreshed {
+				return
+			}
+			select {
+			case <-refreshed:
+			case <-time.After(5 * time.Second):
+				assertions.Fail("not refreshed")
+				return
+			}
+			assertions.Eventually(func() bool {
+				value, found := c.Load("key")
+				return found && value == "fetched"
+			}, 5*time.Second, 10*time.Millisecond, "refreshed value")
+		})
+	}
+}
+
+func TestCache_Range(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, string]
+	c.Store("a", "value", time.Minute)
+	c.Store("b", "value", time.Minute)
+
+	visited := 0
+	c.Range(func(entry *Entry[string, string]) bool {
+		visited++
+		return true
+	})
+	assertions.Equal(2, visited, "visited")
+
+	visited = 0
+	c.Range(func(entry *Entry[string, string]) bool {
+		visited++
+		return false
+	})
+	assertions.Equal(1, visited, "stopped")
+	assertions.Equal(2, c.Stats().Entries, "entries kept")
+}
+
+func TestCache_Purge(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	backend := new(memoryBackend)
+	var c Cache[string, string]
+	c.Persist(backend, "test")
+	c.Store("key", "value", time.Minute)
+
+	c.Purge()
+
+	_, found := c.Load("key")
+	assertions.False(found, "found")
+
+	fetched := false
+	value, err := c.LoadOrFetch(context.Background(), "key", time.Minute, func(ctx context.Context) (value string, err error) {
+		fetched = true
+		return "fetched", nil
+	})
+	if !assertions.Nil(err, "failed to fetch") {
+		return
+	}
+	assertions.True(fetched, "persisted entry purged")
+	assertions.Equal("fetched", value, "value")
+}
+
+func TestCache_PurgeFlight(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, string]
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.LoadOrFetch(context.Background(), "key", time.Minute, func(ctx context.Context) (value string, err error) {
+			<-release
+			return "before purge", nil
+		})
+	}()
+
+	assertions.Eventually(func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.flights) == 1
+	}, time.Second, time.Millisecond, "fetch started")
+
+	c.Purge()
+	close(release)
+	<-done
+
+	_, found := c.Load("key")
+	assertions.False(found, "value fetched before the purge is not cached")
+}
+
+func TestCache_LoadOrFetchCancelled(t *testing.T) {
+	SetDefaults(Defaults{})
+	assertions := assert.New(t)
+
+	var c Cache[string, string]
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	defer close(finish)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.LoadOrFetch(ctx, "key", time.Minute, func(ctx context.Context) (value string, err error) {
+			close(started)
+			// Keeps running after the cancellation
+			<-finish
+			return "", ctx.Err()
+		})
+		done <- err
+	}()
+	<-started
+	cancel()
+	assertions.ErrorIs(<-done, context.Canceled, "cancelled caller")
+
+	// Joining before the cancelled fetch returns starts a new one
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	value, err := c.LoadOrFetch(ctx, "key", time.Minute, func(ctx context.Context) (value string, err error) {
+		return "fetched", nil
+	})
+	if !assertions.Nil(err, "caller after the cancellation") {
+		return
+	}
+	assertions.Equal("fetched", value, "value")
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Pulls the value of a missing key
+type FetchFunc[V any] func(ctx context.Context) (value V, err error)
+
+// NegativeError marks a failed fetch whose result is cached
+type NegativeError struct {
+	Err error
+}
+
+func (e *NegativeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NegativeError) Unwrap() error {
+	return e.Err
+}
+
+// Negative marks the error returned by a fetch to be cached for
+// Defaults.NegativeExpiration, like a missing remote file
+func Negative(err error) error {
+	return &NegativeError{Err: err}
+}
+
+// Fetch in progress shared by the callers of the same key
+type flight[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// LoadOrFetch returns the cached value of the key, calling fetch on a miss.
+// Concurrent misses of the same key share a single fetch. Expired values are
+// served for Defaults.Stale while they are refreshed in the background, and
+// persisted ones as long as the backend keeps them.
+// Errors marked with Negative are cached and returned unwrapped
+func (c *Cache[K, V]) LoadOrFetch(ctx context.Context, key K, timeout time.Duration, fetch FetchFunc[V]) (value V, err error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	c.init()
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*Entry[K, V])
+		switch {
+		case !now.After(entry.Expiration):
+			c.lru.MoveToFront(element)
+			c.hit()
+			c.mu.Unlock()
+			return entry.Value, entry.Err
+		case entry.stale(now):
+			c.lru.MoveToFront(element)
+			c.hit()
+			c.start(ctx, key, timeout, fetch)
+			c.mu.Unlock()
+			return entry.Value, nil
+		}
+	}
+	backend := c.backend
+	c.mu.Unlock()
+
+	// Entries persisted by previous mounts are served like the cached ones.
+	// The backend decides how long they are kept, so expired ones are served
+	// while refreshed however old they are
+	if entry := c.loadPersisted(backend, key, timeout); entry != nil {
+		c.mu.Lock()
+		c.store(entry)
+		c.hit()
+		if now.After(entry.Expiration) {
+			c.start(ctx, key, timeout, fetch)
+		}
+		c.mu.Unlock()
+		return entry.Value, nil
+	}
+
+	c.mu.Lock()
+	c.miss()
+	f := c.start(ctx, key, timeout, fetch)
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Callers arriving later start a new fetch instead of joining
+			// the cancelled one
+			f.cancel()
+			if c.flights[key] == f {
+				delete(c.flights, key)
+			}
+		}
+		c.mu.Unlock()
+		return value, ctx.Err()
+	}
+}
+
+// Returns the fetch in progress of the key, starting it when missing. The
+// fetch is detached from the caller and only cancelled, and forgotten, when
+// every waiter gave up. Must be called holding the lock
+func (c *Cache[K, V]) start(ctx context.Context, key K, timeout time.Duration, fetch FetchFunc[V]) (f *flight[V]) {
+	if f, found := c.flights[key]; found {
+		return f
+	}
+
+	fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f = &flight[V]{done: make(chan struct{}), cancel: cancel}
+	c.flights[key] = f
+	generation := c.generation
+
+	go func() {
+		defer cancel()
+
+		value, err := fetch(fetchCtx)
+		fetchedAt := time.Now()
+
+		var negative *NegativeError
+		c.mu.Lock()
+		backend := c.backend
+		purged := generation != c.generation
+		if purged {
+			backend = nil
+		}
+		switch {
+		case purged:
+			if errors.As(err, &negative) {
+				err = negative.Err
+			}
+		case err == nil:
+			c.store(&Entry[K, V]{Key: key, Value: value, Expiration: fetchedAt.Add(timeout)})
+		case errors.As(err, &negative):
+			err = negative.Err
+			if expiration := time.Duration(defaultNegativeExpiration.Load()); expiration > 0 {
+				c.store(&Entry[K, V]{Key: key, Err: err, Expiration: time.Now().Add(expiration)})
+			}
+		}
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mu.Unlock()
+
+		if err == nil {
+			c.persist(backend, key, value, fetchedAt)
+		}
+
+		f.value, f.err = value, err
+		close(f.done)
+	}()
+	return f
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backend keeps the entries of the caches between mounts
+type Backend interface {
+	Get(key string, value any) (fetchedAt time.Time, found bool, err error)
+	Put(key string, value any, fetchedAt time.Time) (err error)
+	Delete(key string) (err error)
+	// Removes every key of the namespace
+	Purge(namespace string) (err error)
+}
+
+// Persist reads through and writes the entries to the backend. The namespace
+// must be unique to the cache since keys are only unique inside it
+func (c *Cache[K, V]) Persist(backend Backend, namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.backend = backend
+	c.namespace = namespace
+}
+
+func (c *Cache[K, V]) backendKey(key K) (backendKey string) {
+	return c.namespace + "/" + fmt.Sprint(key)
+}
+
+// Loads the persisted entry of the key, nil when missing or not persisted
+func (c *Cache[K, V]) loadPersisted(backend Backend, key K, timeout time.Duration) (entry *Entry[K, V]) {
+	if backend == nil {
+		return nil
+	}
+
+	var value V
+	fetchedAt, found, err := backend.Get(c.backendKey(key), &value)
+	if err != nil {
+		totals.Add("backend-errors", 1)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return &Entry[K, V]{Key: key, Value: value, Expiration: fetchedAt.Add(timeout)}
+}
+
+// Writes the entry to the backend
+func (c *Cache[K, V]) persist(backend Backend, key K, value V, fetchedAt time.Time) {
+	if backend == nil {
+		return
+	}
+
+	err := backend.Put(c.backendKey(key), value, fetchedAt)
+	if err != nil {
+		totals.Add("backend-errors", 1)
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
+)
+
+// Counters of every cache, served under /debug/vars with --debug-addr
+var totals = expvar.NewMap("gsuitefs_cache")
+
+// Settings shared by every cache
+type Defaults struct {
+	// Maximum entries of the caches without their own limit, unlimited when
+	// zero
+	MaxEntries int
+	// Time an expired entry is still served by LoadOrFetch while it is
+	// refreshed in the background
+	Stale time.Duration
+	// Expiration of the failed fetches marked with Negative, not cached when
+	// zero
+	NegativeExpiration time.Duration
+}
+
+var (
+	defaultMaxEntries         atomic.Int64
+	defaultStale              atomic.Int64
+	defaultNegativeExpiration atomic.Int64
+)
+
+func SetDefaults(d Defaults) {
+	defaultMaxEntries.Store(int64(d.MaxEntries))
+	defaultStale.Store(int64(d.Stale))
+	defaultNegativeExpiration.Store(int64(d.NegativeExpiration))
+}
+
+// Caches swept by Sweep. Only weak references are kept so the caches of
+// forgotten nodes are still collected
+var registry struct {
+	mu     sync.Mutex
+	sweeps []func() (alive bool)
+}
+
+type sweepable interface {
+	RemoveExpired() (removed int)
+}
+
+func register[T any, P interface {
+	*T
+	sweepable
+}](c P) {
+	pointer := weak.Make((*T)(c))
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.sweeps = append(registry.sweeps, func() (alive bool) {
+		c := pointer.Value()
+		if c == nil {
+			return false
+		}
+		P(c).RemoveExpired()
+		return true
+	})
+}
+
+// SweepOnce removes the expired entries of every cache
+func SweepOnce() {
+	registry.mu.Lock()
+	sweeps := registry.sweeps
+	registry.mu.Unlock()
+
+	alive := make(map[int]bool, len(sweeps))
+	for index, sweep := range sweeps {
+		alive[index] = sweep()
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	// Caches registered while sweeping are kept
+	kept := registry.sweeps[:0]
+	for index, sweep := range registry.sweeps {
+		if index >= len(sweeps) || alive[index] {
+			kept = append(kept, sweep)
+		}
+	}
+	clear(registry.sweeps[len(kept):])
+	registry.sweeps = kept
+}
+
+// Sweep removes the expired entries of every cache each interval until the
+// context is done
+func Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			SweepOnce()
+		}
+	}
+}
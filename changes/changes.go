@@ -0,0 +1,413 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"weak"
+
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// Fields of the changes required to invalidate the listings
+const ChangeFields = "nextPageToken,newStartPageToken,changes(fileId,removed,file(id,name,mimeType,parents,trashed,sharedWithMeTime,owners(emailAddress)))"
+
+// Maximum wait between the attempts to start a poller
+const MaxBackoff = 10 * time.Minute
+
+type ClientFunc func(ctx context.Context, subject string) (client *http.Client)
+
+// Returns the user impersonated to poll the shared drive
+type DriveSubjectFunc func(ctx context.Context, driveId string) (subject string)
+
+// Invalidator drops the cached state affected by a change
+type Invalidator interface {
+	Invalidate(change *drive.Change)
+}
+
+// Watcher polls the Drive changes of every watched subject and shared drive,
+// dispatching them to the invalidators registered for the changed files and
+// their parents
+type Watcher struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	logger    *slog.Logger
+	client    ClientFunc
+	subject   DriveSubjectFunc
+	interval  time.Duration
+	store     cache.Backend
+	scopes    map[scope]string
+	listeners map[string]map[any]listener
+}
+
+// Invalidator registered for a file, only weakly referenced
+type listener struct {
+	alive      func() (ok bool)
+	invalidate func(change *drive.Change)
+}
+
+// Subject impersonated and shared drive polled, the drive is empty for the
+// personal drive of the subject
+type scope struct {
+	subject string
+	driveId string
+}
+
+// Key of the start page token of the scope in the store
+func (s scope) storeKey() (key string) {
+	return "changes/" + s.subject + "/" + s.driveId
+}
+
+// The shared drives are polled as the subject returned by driveSubject, as
+// the subject passed to Watch when nil. The start page tokens are kept in the
+// store, so the changes missed between mounts are known
+func New(logger *slog.Logger, client ClientFunc, driveSubject DriveSubjectFunc, interval time.Duration, store cache.Backend) (w *Watcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Watcher{
+		ctx:       ctx,
+		cancel:    cancel,
+		logger:    logger.With("context", "changes"),
+		client:    client,
+		subject:   driveSubject,
+		interval:  interval,
+		store:     store,
+		scopes:    make(map[scope]string),
+		listeners: make(map[string]map[any]listener),
+	}
+}
+
+// Close stops every poller
+func (w *Watcher) Close() {
+	if w == nil {
+		return
+	}
+	w.cancel()
+}
+
+// Watch starts polling the changes of the drive, only once per scope. The
+// persisted entries under the namespace are purged when the changes since they
+// were stored are unknown
+func (w *Watcher) Watch(subject, driveId, namespace string) {
+	if w == nil {
+		return
+	}
+
+	s := scope{subject: subject, driveId: driveId}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, found := w.scopes[s]; found {
+		return
+	}
+	w.scopes[s] = namespace
+	go w.poll(s)
+}
+
+// Register dispatches to the invalidator the changes of the file and of the
+// files inside it. Only a weak reference is kept so forgotten nodes are still
+// collected, Sweep forgets them afterwards
+func Register[T any, P interface {
+	*T
+	Invalidator
+}](w *Watcher, fileId string, invalidator P) {
+	if w == nil || fileId == "" {
+		return
+	}
+
+	pointer := weak.Make((*T)(invalidator))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	listeners, found := w.listeners[fileId]
+	if !found {
+		listeners = make(map[any]listener)
+		w.listeners[fileId] = listeners
+	}
+	if _, found := listeners[pointer]; found {
+		return
+	}
+	listeners[pointer] = listener{
+		alive: func() (ok bool) {
+			return pointer.Value() != nil
+		},
+		invalidate: func(change *drive.Change) {
+			if invalidator := pointer.Value(); invalidator != nil {
+				P(invalidator).Invalidate(change)
+			}
+		},
+	}
+}
+
+// Calls every invalidator of the changed file and its parents once
+func (w *Watcher) dispatch(change *drive.Change) {
+	ids := []string{change.FileId}
+	if change.File != nil {
+		ids = append(ids, change.File.Parents...)
+	}
+
+	var (
+		pending []listener
+		seen    = map[any]struct{}{}
+	)
+	w.mu.Lock()
+	for _, id := range ids {
+		for key, l := range w.listeners[id] {
+			if _, found := seen[key]; found {
+				continue
+			}
+			seen[key] = struct{}{}
+			pending = append(pending, l)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, l := range pending {
+		l.invalidate(change)
+	}
+}
+
+// Prune forgets the invalidators already collected, returns how many were
+// removed
+func (w *Watcher) Prune() (removed int) {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, listeners := range w.listeners {
+		for key, l := range listeners {
+			if !l.alive() {
+				delete(listeners, key)
+				removed++
+			}
+		}
+		if len(listeners) == 0 {
+			delete(w.listeners, id)
+		}
+	}
+	return removed
+}
+
+// Sweep prunes the collected invalidators every interval until the context
+// is done
+func (w *Watcher) Sweep(ctx context.Context, interval time.Duration) {
+	if w == nil {
+		return
+	}
+	logger := w.logger.With("action", "Sweep")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removed := w.Prune()
+		logger.Debug("Pruned invalidators", "removed", removed)
+	}
+}
+
+// Drops the persisted entries of the scope
+func (w *Watcher) purge(logger *slog.Logger, s scope) {
+	if w.store == nil {
+		return
+	}
+
+	w.mu.Lock()
+	namespace := w.scopes[s]
+	w.mu.Unlock()
+
+	logger.Debug("Purging persisted entries", "namespace", namespace)
+	err := w.store.Purge(namespace)
+	if err != nil {
+		logger.Warn("failed to purge persisted entries", "error-msg", err)
+	}
+}
+
+// Retrieves a new start page token, the changes before it are lost
+func (w *Watcher) startPageToken(driveSvc *drive.Service, s scope) (token string, err error) {
+	tokenCall := driveSvc.Changes.GetStartPageToken().SupportsAllDrives(true).Context(w.ctx)
+	if s.driveId != "" {
+		tokenCall = tokenCall.DriveId(s.driveId)
+	}
+	startPageToken, err := tokenCall.Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve start page token: %w", err)
+	}
+	return startPageToken.StartPageToken, nil
+}
+
+// Persists the token of the next poll
+func (w *Watcher) storeToken(logger *slog.Logger, s scope, token string) {
+	if w.store == nil {
+		return
+	}
+
+	err := w.store.Put(s.storeKey(), token, time.Now())
+	if err != nil {
+		logger.Warn("failed to persist start page token", "error-msg", err)
+	}
+}
+
+// Prepares the service and the token of the first poll. The token of the
+// previous mount is resumed when persisted
+func (w *Watcher) start(logger *slog.Logger, s scope) (driveSvc *drive.Service, token string, resumed bool, err error) {
+	subject := s.subject
+	if s.driveId != "" && w.subject != nil {
+		subject = w.subject(w.ctx, s.driveId)
+	}
+
+	driveSvc, err = drive.NewService(w.ctx, option.WithHTTPClient(w.client(w.ctx, subject)))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to prepare service: %w", err)
+	}
+
+	if w.store != nil {
+		_, found, err := w.store.Get(s.storeKey(), &token)
+		if err != nil {
+			logger.Warn("failed to read start page token", "error-msg", err)
+		}
+		if found && token != "" {
+			logger.Debug("Resuming start page token")
+			return driveSvc, token, true, nil
+		}
+	}
+
+	logger.Debug("Retrieving start page token")
+	token, err = w.startPageToken(driveSvc, s)
+	if err != nil {
+		return nil, "", false, err
+	}
+	w.purge(logger, s)
+	w.storeToken(logger, s, token)
+	return driveSvc, token, false, nil
+}
+
+func (w *Watcher) poll(s scope) {
+	logger := w.logger.With("action", "poll", "subject", s.subject, "drive-id", s.driveId)
+
+	var (
+		driveSvc *drive.Service
+		token    string
+		resumed  bool
+		err      error
+	)
+	for backoff := w.interval; ; backoff = min(backoff*2, MaxBackoff) {
+		driveSvc, token, resumed, err = w.start(logger, s)
+		if err == nil {
+			break
+		}
+		logger.Error("failed to start polling", "error-msg", err, "retry-in", backoff)
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+
+	if resumed {
+		// Catches up right away, the listeners of the changes missed
+		// between mounts don't exist yet so their entries are purged
+		logger.Debug("Pulling changes since previous mount")
+		next, count, err := w.pull(driveSvc, logger, s, token)
+		switch {
+		case err != nil:
+			logger.Warn("failed to pull missed changes", "error-msg", err)
+			if httputils.IsNotFound(err) {
+				token = w.restart(logger, driveSvc, s, token)
+			}
+		case count > 0:
+			w.purge(logger, s)
+			fallthrough
+		default:
+			token = next
+			w.storeToken(logger, s, token)
+		}
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		next, _, err := w.pull(driveSvc, logger, s, token)
+		if err != nil {
+			logger.Warn("failed to pull changes", "error-msg", err)
+			if httputils.IsNotFound(err) {
+				token = w.restart(logger, driveSvc, s, token)
+			}
+			continue
+		}
+		// Stored on every poll so the store never prunes it
+		token = next
+		w.storeToken(logger, s, token)
+	}
+}
+
+// Replaces an expired token with a new one, the entries persisted before it
+// can't be invalidated anymore
+func (w *Watcher) restart(logger *slog.Logger, driveSvc *drive.Service, s scope, token string) (next string) {
+	logger.Debug("Start page token expired")
+	next, err := w.startPageToken(driveSvc, s)
+	if err != nil {
+		logger.Warn("failed to restart polling", "error-msg", err)
+		return token
+	}
+	w.purge(logger, s)
+	w.storeToken(logger, s, next)
+	return next
+}
+
+// Dispatches the changes since the token, returns the token of the next poll
+// and the number of changes
+func (w *Watcher) pull(driveSvc *drive.Service, logger *slog.Logger, s scope, token string) (next string, count int, err error) {
+	for {
+		call := driveSvc.Changes.
+			List(token).
+			Fields(ChangeFields).
+			IncludeRemoved(true).
+			SupportsAllDrives(true).
+			PageSize(1_000).
+			Context(w.ctx)
+		if s.driveId != "" {
+			call = call.DriveId(s.driveId).IncludeItemsFromAllDrives(true)
+		}
+
+		changeList, err := call.Do()
+		if err != nil {
+			return token, count, err
+		}
+
+		for _, change := range changeList.Changes {
+			logger.Debug("Dispatching change", "file-id", change.FileId, "removed", change.Removed)
+			w.dispatch(change)
+		}
+		count += len(changeList.Changes)
+
+		if changeList.NextPageToken == "" {
+			return changeList.NewStartPageToken, count, nil
+		}
+		token = changeList.NextPageToken
+	}
+}
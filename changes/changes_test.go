@@ -0,0 +1,265 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+// Backend keeping the entries in memory, recording the purged namespaces
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]string
+	purged  []string
+}
+
+func (b *memoryBackend) Get(key string, value any) (fetchedAt time.Time, found bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, found := b.entries[key]
+	if !found {
+		return fetchedAt, false, nil
+	}
+	*(value.(*string)) = stored
+	return time.Now(), true, nil
+}
+
+func (b *memoryBackend) Put(key string, value any, fetchedAt time.Time) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.entries == nil {
+		b.entries = make(map[string]string)
+	}
+	b.entries[key] = value.(string)
+	return nil
+}
+
+func (b *memoryBackend) Delete(key string) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, key)
+	return nil
+}
+
+func (b *memoryBackend) Purge(namespace string) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.purged = append(b.purged, namespace)
+	return nil
+}
+
+func (b *memoryBackend) token(key string) (token string, purged []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.entries[key], append([]string(nil), b.purged...)
+}
+
+// Transport answering the Drive changes requests
+type driveTransport struct {
+	startPageToken string
+	changes        []*drive.Change
+	requests       atomic.Int64
+}
+
+func (t *driveTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	t.requests.Add(1)
+
+	var body any
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/changes/startPageToken"):
+		body = &drive.StartPageToken{StartPageToken: t.startPageToken}
+	case strings.HasSuffix(req.URL.Path, "/changes"):
+		body = &drive.ChangeList{Changes: t.changes, NewStartPageToken: t.startPageToken}
+	default:
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: req}, nil
+	}
+
+	contents, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(contents))),
+		Request:    req,
+	}, nil
+}
+
+func newWatcher(transport *driveTransport, store *memoryBackend) (w *Watcher) {
+	client := func(ctx context.Context, subject string) (client *http.Client) {
+		return &http.Client{Transport: transport}
+	}
+	return New(slog.New(slog.DiscardHandler), client, nil, time.Hour, store)
+}
+
+// Invalidator recording the changes received
+type recorder struct {
+	mu      sync.Mutex
+	changes []*drive.Change
+}
+
+func (r *recorder) Invalidate(change *drive.Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.changes = append(r.changes, change)
+}
+
+func (r *recorder) received() (count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.changes)
+}
+
+func TestWatcher_dispatch(t *testing.T) {
+	t.Run("Parents", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		w := newWatcher(new(driveTransport), new(memoryBackend))
+		defer w.Close()
+
+		file, folder, both, unrelated := new(recorder), new(recorder), new(recorder), new(recorder)
+		Register(w, "file", file)
+		Register(w, "folder", folder)
+		Register(w, "file", both)
+		Register(w, "folder", both)
+		Register(w, "other", unrelated)
+
+		w.dispatch(&drive.Change{FileId: "file", File: &drive.File{Id: "file", Parents: []string{"folder"}}})
+		assertions.Equal(1, file.received(), "file")
+		assertions.Equal(1, folder.received(), "folder")
+		assertions.Equal(1, both.received(), "registered twice")
+		assertions.Equal(0, unrelated.received(), "unrelated")
+
+		// Removed files have no parents
+		w.dispatch(&drive.Change{FileId: "file", Removed: true})
+		assertions.Equal(2, file.received(), "file removed")
+		assertions.Equal(1, folder.received(), "folder of a removed file")
+	})
+	t.Run("Collected", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		w := newWatcher(new(driveTransport), new(memoryBackend))
+		defer w.Close()
+
+		kept := new(recorder)
+		Register(w, "file", kept)
+		func() {
+			Register(w, "file", new(recorder))
+			Register(w, "folder", new(recorder))
+		}()
+		runtime.GC()
+
+		assertions.Equal(2, w.Prune(), "removed")
+		assertions.Len(w.listeners, 1, "files")
+		assertions.Len(w.listeners["file"], 1, "listeners")
+
+		w.dispatch(&drive.Change{FileId: "file"})
+		assertions.Equal(1, kept.received(), "kept")
+		runtime.KeepAlive(kept)
+	})
+}
+
+func TestWatcher_start(t *testing.T) {
+	s := scope{subject: "user@example.com"}
+
+	t.Run("Fresh", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		transport := &driveTransport{startPageToken: "10"}
+		store := new(memoryBackend)
+		w := newWatcher(transport, store)
+		defer w.Close()
+		w.scopes[s] = "directory/user/1"
+
+		_, token, resumed, err := w.start(slog.New(slog.DiscardHandler), s)
+		if !assertions.Nil(err, "failed to start") {
+			return
+		}
+		assertions.False(resumed, "resumed")
+		assertions.Equal("10", token, "token")
+
+		stored, purged := store.token(s.storeKey())
+		assertions.Equal("10", stored, "stored token")
+		assertions.Equal([]string{"directory/user/1"}, purged, "purged")
+	})
+	t.Run("Resumed", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		transport := &driveTransport{startPageToken: "10"}
+		store := new(memoryBackend)
+		store.Put(s.storeKey(), "5", time.Now())
+		w := newWatcher(transport, store)
+		defer w.Close()
+		w.scopes[s] = "directory/user/1"
+
+		_, token, resumed, err := w.start(slog.New(slog.DiscardHandler), s)
+		if !assertions.Nil(err, "failed to start") {
+			return
+		}
+		assertions.True(resumed, "resumed")
+		assertions.Equal("5", token, "token")
+		assertions.Equal(int64(0), transport.requests.Load(), "requests")
+
+		_, purged := store.token(s.storeKey())
+		assertions.Empty(purged, "purged")
+	})
+}
+
+func TestWatcher_poll(t *testing.T) {
+	s := scope{subject: "user@example.com"}
+
+	type Test struct {
+		Name    string
+		Changes []*drive.Change
+		Purged  []string
+	}
+	tests := []Test{
+		{
+			Name: "Unchanged",
+		},
+		{
+			Name:    "Missed changes",
+			Changes: []*drive.Change{{FileId: "file"}},
+			Purged:  []string{"directory/user/1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			transport := &driveTransport{startPageToken: "11", changes: test.Changes}
+			store := new(memoryBackend)
+			store.Put(s.storeKey(), "5", time.Now())
+			w := newWatcher(transport, store)
+			defer w.Close()
+
+			w.Watch(s.subject, s.driveId, "directory/user/1")
+			assertions.Eventually(func() bool {
+				token, _ := store.token(s.storeKey())
+				return token == "11"
+			}, 5*time.Second, 10*time.Millisecond, "caught up")
+
+			_, purged := store.token(s.storeKey())
+			assertions.Equal(test.Purged, purged, "purged")
+		})
+	}
+}
@@ -3,7 +3,10 @@ package main
 import "github.com/pluto-org-co/gsuitefs/filesystem/config"
 
 type Config struct {
-	AdministratorSubject string         `yaml:"administrator-subject"`
-	ServiceAccountFile   string         `yaml:"service-account-file"`
-	Include              config.Include `yaml:"include"`
+	AdministratorSubject string          `yaml:"administrator-subject"`
+	ServiceAccountFile   string          `yaml:"service-account-file"`
+	Include              config.Include  `yaml:"include"`
+	Export               config.Export   `yaml:"export"`
+	Cache                config.Cache    `yaml:"cache"`
+	Timeouts             config.Timeouts `yaml:"timeouts"`
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/urfave/cli/v3"
@@ -28,11 +29,21 @@ var ExampleCmd = cli.Command{
 					},
 					Groups: &config.IncludeGroups{},
 				},
-				SharedDrives: &config.IncludeDrive{
+				SharedDrives: &config.IncludeSharedDrives{
 					Active:  true,
 					Trashed: true,
 				},
 			},
+			Cache: config.Cache{
+				Path:            "/var/cache/gsuitefs",
+				Expiration:      time.Minute,
+				BlockSize:       4 << 20,
+				ReadAheadBlocks: 4,
+			},
+			Export: config.Export{
+				Formats:    config.DefaultExportFormats,
+				AllFormats: false,
+			},
 		}
 
 		contents, err := yaml.Marshal(&cfg)
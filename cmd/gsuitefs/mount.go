@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/pluto-org-co/gsuitefs"
+	"github.com/pluto-org-co/gsuitefs/cache"
 	"github.com/pluto-org-co/gsuitefs/filesystem"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/pluto-org-co/gsuitefs/httputils"
@@ -29,10 +31,30 @@ const (
 	ConfigFlag     = "config"
 	ForegroundFlag = "foreground"
 	LogLevelFlag   = "log-level"
+	DebugAddrFlag  = "debug-addr"
 )
 
 var homedir, _ = os.UserHomeDir()
 
+// Serves the expvar counters under /debug/vars until the context is done
+func serveDebug(ctx context.Context, logger *slog.Logger, addr string) {
+	logger = logger.With("action", "serveDebug", "addr", addr)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logger.Debug("Serving debug variables")
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("failed to serve debug variables", "error-msg", err)
+	}
+}
+
 func doMount(ctx context.Context, c *cli.Command) (err error) {
 	mountpoint := c.StringArg(MountpointArg)
 	if mountpoint == "" {
@@ -69,6 +91,9 @@ func doMount(ctx context.Context, c *cli.Command) (err error) {
 	var fsConfig = config.Config{
 		AdministratorSubject: yamlConfig.AdministratorSubject,
 		Include:              yamlConfig.Include,
+		Export:               yamlConfig.Export,
+		Cache:                yamlConfig.Cache,
+		Timeouts:             yamlConfig.Timeouts,
 	}
 
 	svcAccountContents, err := os.ReadFile(yamlConfig.ServiceAccountFile)
@@ -76,7 +101,8 @@ func doMount(ctx context.Context, c *cli.Command) (err error) {
 		return fmt.Errorf("failed to read service account file: %w", err)
 	}
 
-	_, err = google.JWTConfigFromJSON(svcAccountContents, gsuitefs.Scopes...)
+	scopes := gsuitefs.ScopesFor(&yamlConfig.Include)
+	_, err = google.JWTConfigFromJSON(svcAccountContents, scopes...)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration from file: %w", err)
 	}
@@ -85,7 +111,7 @@ func doMount(ctx context.Context, c *cli.Command) (err error) {
 		logger.With("action", "Creating HTTP client", "subject", subject)
 
 		logger.Debug("Importing JWT Config")
-		conf, _ := google.JWTConfigFromJSON(svcAccountContents, gsuitefs.Scopes...)
+		conf, _ := google.JWTConfigFromJSON(svcAccountContents, scopes...)
 		conf.Subject = subject
 
 		logger.Debug("Generating HTTP Client")
@@ -99,22 +125,43 @@ func doMount(ctx context.Context, c *cli.Command) (err error) {
 		return fmt.Errorf("failed to prepare root filesystem: %w", err)
 	}
 
+	sweepCtx, cancelSweep := context.WithCancel(ctx)
+	defer cancelSweep()
+	go cache.Sweep(sweepCtx, fsConfig.Cache.SweepInterval)
+	go fsConfig.MetadataStore.Sweep(sweepCtx, fsConfig.Cache.SweepInterval)
+	go fsConfig.Changes.Sweep(sweepCtx, fsConfig.Cache.SweepInterval)
+
+	if addr := c.String(DebugAddrFlag); addr != "" {
+		go serveDebug(sweepCtx, logger, addr)
+	}
+
 	var options fs.Options
 	options.FirstAutomaticIno = 1
 	options.UID = uint32(os.Getuid())
 	options.GID = uint32(os.Getgid())
 	options.FsName = mountpoint
 	options.Name = "gsuitefs"
-	timeout := 10 * time.Second
-	options.EntryTimeout = &timeout
+	options.EntryTimeout = &fsConfig.Timeouts.Default.Entry
+	if fsConfig.Timeouts.Default.Attr > 0 {
+		options.AttrTimeout = &fsConfig.Timeouts.Default.Attr
+	}
+	if fsConfig.Timeouts.Negative > 0 {
+		options.NegativeTimeout = &fsConfig.Timeouts.Negative
+	}
 	server, err := fs.Mount(mountpoint, root, &options)
 	if err != nil {
+		root.Close()
 		return fmt.Errorf("failed to mount filesystem: %w", err)
 	}
 
 	defer server.Unmount()
 
 	server.Wait()
+
+	err = root.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close filesystem: %w", err)
+	}
 	return nil
 }
 
@@ -143,6 +190,12 @@ var MountCmd = cli.Command{
 			Usage:    "Log level to use by slog (-4:Debug, 0: Info, 4: Warn, 8: Error)",
 			Value:    int(slog.LevelInfo),
 		},
+		&cli.StringFlag{
+			Name:     DebugAddrFlag,
+			Category: "Runtime",
+			OnlyOnce: true,
+			Usage:    "Address serving the cache and verification counters under /debug/vars, disabled when empty (localhost:6060)",
+		},
 		&cli.BoolFlag{
 			Name:     ForegroundFlag,
 			Category: "Runtime",
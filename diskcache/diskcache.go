@@ -0,0 +1,293 @@
+package diskcache
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Config struct {
+	Path string
+	// Maximum bytes used by the cache, unlimited when zero
+	MaxSize int64
+	// Bytes left free in the cache file system, ignored when zero
+	MinFreeSpace int64
+	// Suffixes of the files stored next to a cache file and evicted with it
+	Sidecars []string
+	// Suffixes of incomplete files, removed by the startup scan
+	Temporaries []string
+	// Directories under Path not managed by the cache
+	Ignored []string
+}
+
+// Cache file with its sidecars
+type group struct {
+	key        string
+	size       int64
+	accessTime time.Time
+	open       int
+	element    *list.Element
+}
+
+// Manager bounds the size of the content cache evicting the least recently
+// accessed files. Files currently open are never evicted
+type Manager struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+	config Config
+	size   int64
+	groups map[string]*group
+	// Least recently accessed at the back
+	lru *list.List
+}
+
+func New(logger *slog.Logger, c Config) (m *Manager, err error) {
+	m = &Manager{
+		logger: logger.With("context", "diskcache", "path", c.Path),
+		config: c,
+		groups: make(map[string]*group),
+		lru:    list.New(),
+	}
+
+	err = m.scan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache: %w", err)
+	}
+
+	m.mu.Lock()
+	m.evict()
+	m.mu.Unlock()
+	return m, nil
+}
+
+// Rebuilds the index from the files already in the cache directory
+func (m *Manager) scan() (err error) {
+	logger := m.logger.With("action", "scan")
+
+	var scanned []*group
+	err = filepath.WalkDir(m.config.Path, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && filename != m.config.Path && slices.Contains(m.config.Ignored, entry.Name()) {
+			logger.Debug("Ignoring directory", "filename", filename)
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		if hasSuffix(filename, m.config.Temporaries) {
+			logger.Debug("Removing incomplete file", "filename", filename)
+			err = os.Remove(filename)
+			if err != nil && !os.IsNotExist(err) {
+				logger.Warn("Failed to remove incomplete file", "filename", filename, "error-msg", err)
+			}
+			return nil
+		}
+
+		key := m.key(filename)
+		g, found := m.groups[key]
+		if !found {
+			g = &group{key: key}
+			m.groups[key] = g
+			scanned = append(scanned, g)
+		}
+
+		var stat syscall.Stat_t
+		err = syscall.Lstat(filename, &stat)
+		if err != nil {
+			return fmt.Errorf("failed to stat: %s: %w", filename, err)
+		}
+		g.size += usage(&stat)
+		accessTime := time.Unix(stat.Atim.Unix())
+		if accessTime.After(g.accessTime) {
+			g.accessTime = accessTime
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Most recently accessed first
+	for _, g := range scanned {
+		m.size += g.size
+		element := m.lru.Front()
+		for element != nil && element.Value.(*group).accessTime.After(g.accessTime) {
+			element = element.Next()
+		}
+		if element == nil {
+			g.element = m.lru.PushBack(g)
+		} else {
+			g.element = m.lru.InsertBefore(g, element)
+		}
+	}
+
+	logger.Info("Cache scanned", "files", len(m.groups), "size", m.size)
+	return nil
+}
+
+// Cache filename the file belongs to
+func (m *Manager) key(filename string) (key string) {
+	longest := ""
+	for _, suffix := range m.config.Sidecars {
+		if strings.HasSuffix(filename, suffix) && len(suffix) > len(longest) {
+			longest = suffix
+		}
+	}
+	return strings.TrimSuffix(filename, longest)
+}
+
+func hasSuffix(filename string, suffixes []string) (found bool) {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(filename, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes allocated in disk, blocks files are sparse
+func usage(stat *syscall.Stat_t) (size int64) {
+	return stat.Blocks * 512
+}
+
+// Returns the group of the file moved to the front of the LRU
+func (m *Manager) touch(filename string) (g *group) {
+	key := m.key(filename)
+	g, found := m.groups[key]
+	if !found {
+		g = &group{key: key}
+		m.groups[key] = g
+		g.element = m.lru.PushFront(g)
+	} else {
+		m.lru.MoveToFront(g.element)
+	}
+	g.accessTime = time.Now()
+	return g
+}
+
+// Touch marks the cache file as recently accessed
+func (m *Manager) Touch(filename string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.touch(filename)
+}
+
+// Acquire protects the cache file from eviction until released
+func (m *Manager) Acquire(filename string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.touch(filename).open++
+}
+
+// Release allows the eviction of the cache file again and refreshes its size
+func (m *Manager) Release(filename string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g := m.touch(filename)
+	if g.open > 0 {
+		g.open--
+	}
+	m.update(g)
+	m.evict()
+}
+
+// Update refreshes the size of the cache file after writing it and evicts
+// files when required
+func (m *Manager) Update(filename string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.update(m.touch(filename))
+	m.evict()
+}
+
+func (m *Manager) update(g *group) {
+	var size int64
+	for _, suffix := range append([]string{""}, m.config.Sidecars...) {
+		var stat syscall.Stat_t
+		err := syscall.Lstat(g.key+suffix, &stat)
+		if err == nil {
+			size += usage(&stat)
+		}
+	}
+	m.size += size - g.size
+	g.size = size
+}
+
+// Reports if the limits are exceeded
+func (m *Manager) exceeded() (ok bool) {
+	if m.config.MaxSize > 0 && m.size > m.config.MaxSize {
+		return true
+	}
+	if m.config.MinFreeSpace > 0 {
+		var stat syscall.Statfs_t
+		err := syscall.Statfs(m.config.Path, &stat)
+		if err != nil {
+			m.logger.Warn("Failed to get free space", "error-msg", err)
+			return false
+		}
+		if int64(stat.Bavail)*stat.Bsize < m.config.MinFreeSpace {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the least recently accessed files until the limits are satisfied
+func (m *Manager) evict() {
+	logger := m.logger.With("action", "evict")
+
+	element := m.lru.Back()
+	for element != nil && m.exceeded() {
+		g := element.Value.(*group)
+		element = element.Prev()
+		if g.open > 0 {
+			continue
+		}
+
+		logger.Debug("Evicting file", "filename", g.key, "size", g.size)
+		for _, suffix := range append([]string{""}, m.config.Sidecars...) {
+			err := os.Remove(g.key + suffix)
+			if err != nil && !os.IsNotExist(err) {
+				logger.Warn("Failed to remove file", "filename", g.key+suffix, "error-msg", err)
+			}
+		}
+		m.size -= g.size
+		m.lru.Remove(g.element)
+		delete(m.groups, g.key)
+	}
+
+	if m.exceeded() {
+		logger.Warn("Cache limits exceeded by open files", "size", m.size)
+	}
+}
@@ -0,0 +1,203 @@
+package diskcache
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	recordSuffix = ".record.json"
+	partSuffix   = ".part"
+	ignoredDir   = "metadata"
+	fileSize     = 64 << 10
+)
+
+func testConfig(path string, maxSize int64) (c Config) {
+	return Config{
+		Path:        path,
+		MaxSize:     maxSize,
+		Sidecars:    []string{recordSuffix},
+		Temporaries: []string{partSuffix},
+		Ignored:     []string{ignoredDir},
+	}
+}
+
+// Writes a file of fileSize bytes last accessed at the time, returns the
+// bytes it uses
+func writeFile(t *testing.T, filename string, accessTime time.Time) (size int64) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(filename), 0o700)
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	err = os.WriteFile(filename, make([]byte, fileSize), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	err = os.Chtimes(filename, accessTime, accessTime)
+	if err != nil {
+		t.Fatalf("failed to change times: %v", err)
+	}
+
+	var stat syscall.Stat_t
+	err = syscall.Lstat(filename, &stat)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	return usage(&stat)
+}
+
+func exists(filename string) (ok bool) {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+func TestManager_scan(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	now := time.Now()
+	var size int64
+	size += writeFile(t, filepath.Join(path, "older"), now.Add(-time.Hour))
+	size += writeFile(t, filepath.Join(path, "older"+recordSuffix), now.Add(-time.Hour))
+	size += writeFile(t, filepath.Join(path, "newer"), now)
+	writeFile(t, filepath.Join(path, "newer.abc"+partSuffix), now)
+	writeFile(t, filepath.Join(path, ignoredDir, "ab", "record.json"), now.Add(-time.Hour))
+
+	m, err := New(slog.New(slog.DiscardHandler), testConfig(path, 0))
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	assertions.Len(m.groups, 2, "groups")
+	assertions.Equal(size, m.size, "size")
+	assertions.Equal(filepath.Join(path, "newer"), m.lru.Front().Value.(*group).key, "most recently accessed")
+	assertions.Equal(filepath.Join(path, "older"), m.lru.Back().Value.(*group).key, "least recently accessed")
+	assertions.False(exists(filepath.Join(path, "newer.abc"+partSuffix)), "incomplete file removed")
+	assertions.True(exists(filepath.Join(path, ignoredDir, "ab", "record.json")), "ignored file kept")
+}
+
+func TestManager_scanEvict(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	now := time.Now()
+	writeFile(t, filepath.Join(path, "older"), now.Add(-time.Hour))
+	writeFile(t, filepath.Join(path, "older"+recordSuffix), now.Add(-time.Hour))
+	size := writeFile(t, filepath.Join(path, "newer"), now)
+	writeFile(t, filepath.Join(path, ignoredDir, "ab", "record.json"), now.Add(-2*time.Hour))
+
+	m, err := New(slog.New(slog.DiscardHandler), testConfig(path, size))
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	assertions.Equal(size, m.size, "size")
+	assertions.False(exists(filepath.Join(path, "older")), "least recently accessed evicted")
+	assertions.False(exists(filepath.Join(path, "older"+recordSuffix)), "sidecar evicted")
+	assertions.True(exists(filepath.Join(path, "newer")), "most recently accessed kept")
+	assertions.True(exists(filepath.Join(path, ignoredDir, "ab", "record.json")), "ignored file kept")
+}
+
+func TestManager_Update(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	size := writeFile(t, filepath.Join(t.TempDir(), "probe"), time.Now())
+	m, err := New(slog.New(slog.DiscardHandler), testConfig(path, 2*size))
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	a, b, c := filepath.Join(path, "a"), filepath.Join(path, "b"), filepath.Join(path, "c")
+	writeFile(t, a, time.Now())
+	m.Update(a)
+	writeFile(t, b, time.Now())
+	m.Update(b)
+	assertions.Equal(2*size, m.size, "size")
+
+	// The sidecar is counted with its cache file
+	writeFile(t, a+recordSuffix, time.Now())
+	m.Update(a)
+	assertions.False(exists(b), "least recently accessed evicted")
+	assertions.True(exists(a), "cache file kept")
+	assertions.True(exists(a+recordSuffix), "sidecar kept")
+	assertions.Equal(2*size, m.size, "size with sidecar")
+
+	writeFile(t, c, time.Now())
+	m.Update(c)
+	assertions.False(exists(a), "cache file evicted")
+	assertions.False(exists(a+recordSuffix), "sidecar evicted with its cache file")
+	assertions.True(exists(c), "most recently accessed kept")
+	assertions.Equal(size, m.size, "size after eviction")
+}
+
+func TestManager_Touch(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	size := writeFile(t, filepath.Join(t.TempDir(), "probe"), time.Now())
+	m, err := New(slog.New(slog.DiscardHandler), testConfig(path, 2*size))
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	a, b, c := filepath.Join(path, "a"), filepath.Join(path, "b"), filepath.Join(path, "c")
+	writeFile(t, a, time.Now())
+	m.Update(a)
+	writeFile(t, b, time.Now())
+	m.Update(b)
+	m.Touch(a)
+
+	writeFile(t, c, time.Now())
+	m.Update(c)
+	assertions.True(exists(a), "recently touched kept")
+	assertions.False(exists(b), "least recently accessed evicted")
+	assertions.True(exists(c), "most recently accessed kept")
+}
+
+func TestManager_Acquire(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	size := writeFile(t, filepath.Join(t.TempDir(), "probe"), time.Now())
+	m, err := New(slog.New(slog.DiscardHandler), testConfig(path, 2*size))
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	a, b, c, d := filepath.Join(path, "a"), filepath.Join(path, "b"), filepath.Join(path, "c"), filepath.Join(path, "d")
+	m.Acquire(a)
+	writeFile(t, a, time.Now())
+	m.Update(a)
+	writeFile(t, b, time.Now())
+	m.Update(b)
+	writeFile(t, c, time.Now())
+	m.Update(c)
+	assertions.True(exists(a), "open file kept")
+	assertions.False(exists(b), "least recently accessed closed file evicted")
+	assertions.True(exists(c), "most recently accessed kept")
+
+	m.Release(a)
+	m.Touch(c)
+	writeFile(t, d, time.Now())
+	m.Update(d)
+	assertions.False(exists(a), "released file evicted")
+	assertions.True(exists(c), "recently touched kept")
+	assertions.True(exists(d), "most recently accessed kept")
+}
+
+func TestManager_Nil(t *testing.T) {
+	var m *Manager
+	m.Touch("a")
+	m.Acquire("a")
+	m.Update("a")
+	m.Release("a")
+}
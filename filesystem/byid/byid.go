@@ -0,0 +1,315 @@
+package byid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/directory"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/exports"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const NodeName = directory.ByIdDirName
+
+// Fields requested for every file reached by ID
+const FileFields = directory.FileFields + ",driveId,trashed"
+
+// Maximum number of subjects impersonated searching a file not visible to
+// the administrator
+const MaxSearchedSubjects = 100
+
+const SubjectsCacheKey = 0
+
+// Characters of the Drive file IDs, other names are never searched
+var fileIdRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// File reached by ID with the user able to read it, the user is nil for the
+// files of shared drives
+type target struct {
+	File *drive.File `json:"file"`
+	User *admin.User `json:"user,omitempty"`
+}
+
+// ById exposes every Drive file and folder of the organization by its ID. The
+// files are fetched as the administrator and, when not visible to it, as the
+// subjects of the shared drives and then the users of the organization, up to
+// MaxSearchedSubjects. Only the files already looked up are listed
+type ById struct {
+	fs.Inode
+
+	lookupCache   cache.Cache[string, *target]
+	subjectsCache cache.Cache[int, []string]
+	logger        *slog.Logger
+	config        *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config) (b *ById) {
+	b = &ById{logger: logger.With("inode", NodeName), config: c}
+	b.lookupCache.Persist(c.MetadataStore, "by-id/lookup")
+	b.subjectsCache.Persist(c.MetadataStore, "by-id/subjects")
+	return b
+}
+
+var _ fs.NodeLookuper = (*ById)(nil)
+
+// Retrieves the file impersonating the subject, nil when not visible to it
+func (b *ById) getFile(ctx context.Context, subject, fileId string) (file *drive.File, err error) {
+	client := b.config.HttpClientProviderFunc(ctx, subject)
+
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare drive service: %w", err)
+	}
+
+	file, err = driveSvc.Files.
+		Get(fileId).
+		Fields(FileFields).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	if err != nil {
+		if httputils.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Subjects impersonated searching a file: the members of the shared drives
+// first, then the users of the organization
+func (b *ById) subjects(ctx context.Context, logger *slog.Logger, adminSvc *admin.Service) (subjects []string, err error) {
+	return b.subjectsCache.LoadOrFetch(ctx, SubjectsCacheKey, b.config.Cache.Expiration, func(ctx context.Context) (subjects []string, err error) {
+		add := func(subject string) (full bool) {
+			if subject != "" && subject != b.config.AdministratorSubject && !slices.Contains(subjects, subject) {
+				subjects = append(subjects, subject)
+			}
+			return len(subjects) >= MaxSearchedSubjects
+		}
+
+		// Without domain admin access every shared drive is read as the
+		// administrator, which already failed
+		if b.config.Organizers != nil {
+			client := b.config.HttpClientProviderFunc(ctx, b.config.AdministratorSubject)
+
+			logger.Debug("Preparing drive service")
+			driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare drive service: %w", err)
+			}
+
+			logger.Debug("Listing shared drive subjects")
+			err = driveSvc.Drives.
+				List().
+				UseDomainAdminAccess(true).
+				PageSize(100).
+				Context(ctx).
+				Pages(ctx, func(dl *drive.DriveList) (err error) {
+					for _, sharedDrive := range dl.Drives {
+						if add(b.config.SharedDriveSubject(ctx, sharedDrive.Id)) {
+							return io.EOF
+						}
+					}
+					return nil
+				})
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to list shared drives: %w", err)
+			}
+		}
+
+		logger.Debug("Listing users")
+		err = adminSvc.Users.
+			List().
+			Customer("my_customer").
+			Context(ctx).
+			Pages(ctx, func(ul *admin.Users) (err error) {
+				for _, user := range ul.Users {
+					if user.Suspended {
+						continue
+					}
+					if add(user.PrimaryEmail) {
+						return io.EOF
+					}
+				}
+				return nil
+			})
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to list users: %w", err)
+		}
+		return subjects, nil
+	})
+}
+
+// Finds a subject able to read the file
+func (b *ById) search(ctx context.Context, logger *slog.Logger, adminSvc *admin.Service, fileId string) (file *drive.File, subject string, err error) {
+	subjects, err := b.subjects(ctx, logger, adminSvc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger.Debug("Searching subject with access", "subjects", len(subjects))
+	for _, subject := range subjects {
+		file, err = b.getFile(ctx, subject, fileId)
+		if err != nil {
+			logger.Debug("Failed to retrieve file as subject", "subject", subject, "error-msg", err)
+			continue
+		}
+		if file != nil {
+			return file, subject, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// Resolves the file and the user impersonated to read it, preferring the
+// owner when it belongs to the organization
+func (b *ById) resolve(ctx context.Context, logger *slog.Logger, fileId string) (t *target, err error) {
+	client := b.config.HttpClientProviderFunc(ctx, b.config.AdministratorSubject)
+
+	logger.Debug("Preparing admin service")
+	adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Retrieving file as administrator")
+	file, err := b.getFile(ctx, b.config.AdministratorSubject, fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject string
+	if file == nil {
+		file, subject, err = b.search(ctx, logger, adminSvc, fileId)
+		if err != nil {
+			return nil, err
+		}
+		if file == nil {
+			return nil, cache.Negative(syscall.ENOENT)
+		}
+	}
+
+	// Shared drive files are read as the drive subject
+	if file.DriveId != "" {
+		return &target{File: file}, nil
+	}
+
+	if len(file.Owners) > 0 && file.Owners[0].EmailAddress != "" {
+		owner, err := adminSvc.Users.Get(file.Owners[0].EmailAddress).Context(ctx).Do()
+		if err == nil {
+			return &target{File: file, User: owner}, nil
+		}
+		logger.Debug("Owner outside of the organization", "owner", file.Owners[0].EmailAddress, "error-msg", err)
+	}
+	if subject == "" {
+		subject = b.config.AdministratorSubject
+	}
+
+	user, err := adminSvc.Users.Get(subject).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return &target{File: file, User: user}, nil
+}
+
+func (b *ById) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := b.logger.With("action", "Lookup", "name", name)
+
+	if !fileIdRegexp.MatchString(name) {
+		logger.Debug("Not a file ID")
+		return nil, syscall.ENOENT
+	}
+
+	// Every miss searches the subjects, resolve returns a negative error so
+	// it is cached like the files found
+	logger.Debug("Loading file")
+	t, err := b.lookupCache.LoadOrFetch(ctx, name, b.config.Cache.Expiration, func(ctx context.Context) (t *target, err error) {
+		return b.resolve(ctx, logger, name)
+	})
+	if err != nil {
+		logger.Error("failed to retrieve file", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	file := t.File
+	var sharedDrive *drive.Drive
+	if file.DriveId != "" {
+		sharedDrive = &drive.Drive{Id: file.DriveId}
+	}
+
+	switch file.MimeType {
+	case directory.FolderMimeType:
+		b.config.Timeouts.Folders.EntryOut(out)
+		attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindDriveFile, file.Id), Gen: uint64(file.Version)}
+		if node = inodes.Reuse(&b.Inode, name, attr); node != nil {
+			logger.Debug("Reusing inode")
+			return node, fs.OK
+		}
+
+		cfg := directory.Config{
+			Logger:    b.logger,
+			Config:    b.config,
+			User:      t.User,
+			Drive:     sharedDrive,
+			Trashed:   file.Trashed,
+			Directory: file,
+		}
+		node = b.NewInode(ctx, directory.New(&cfg), attr)
+	case directory.ShortcutMimeType:
+		if file.ShortcutDetails == nil {
+			logger.Error("Missing shortcut details")
+			return nil, syscall.EIO
+		}
+
+		// Shortcuts point to their target next to them
+		b.config.Timeouts.Files.EntryOut(out)
+		attr := fs.StableAttr{Mode: syscall.S_IFLNK, Ino: inodes.Ino(inodes.KindDriveFile, file.Id), Gen: uint64(file.Version)}
+		if node = inodes.Reuse(&b.Inode, name, attr); node != nil {
+			logger.Debug("Reusing inode")
+			return node, fs.OK
+		}
+		node = b.NewInode(ctx, &fs.MemSymlink{Data: []byte(file.ShortcutDetails.TargetId)}, attr)
+	default:
+		b.config.Timeouts.Files.EntryOut(out)
+		var exportMimeType string
+		ino := inodes.Ino(inodes.KindDriveFile, file.Id)
+		if exports.IsNative(file) {
+			exportMimeType = exports.Preferred(&b.config.Export, file)
+		}
+		if exportMimeType != "" {
+			ino = inodes.Ino(inodes.KindDriveFile, file.Id, exportMimeType)
+		}
+		attr := fs.StableAttr{Mode: syscall.S_IFREG, Ino: ino, Gen: uint64(file.Version)}
+		if node = inodes.Reuse(&b.Inode, name, attr); node != nil {
+			logger.Debug("Reusing inode")
+			return node, fs.OK
+		}
+
+		cfg := files.Config{
+			Logger:         b.logger,
+			Config:         b.config,
+			User:           t.User,
+			Drive:          sharedDrive,
+			Trashed:        file.Trashed,
+			File:           file,
+			ExportMimeType: exportMimeType,
+		}
+		node = b.NewInode(ctx, files.New(&cfg), attr)
+	}
+	return node, fs.OK
+}
@@ -0,0 +1,42 @@
+package byid
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestById_LookupNotFileId(t *testing.T) {
+	type Test struct {
+		Name  string
+		Entry string
+	}
+	tests := []Test{
+		{Name: "Hidden", Entry: ".Trash"},
+		{Name: "Dot", Entry: "."},
+		{Name: "Spaces", Entry: "My Drive"},
+		{Name: "Extension", Entry: "1AbCdEf.pdf"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			// Names that aren't file IDs never reach the API
+			b := &ById{logger: slog.New(slog.DiscardHandler)}
+			node, errno := b.Lookup(context.Background(), test.Entry, &fuse.EntryOut{})
+			assertions.Nil(node, "node")
+			assertions.Equal(syscall.ENOENT, errno, "errno")
+		})
+	}
+}
+
+func TestFileIdRegexp(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.True(fileIdRegexp.MatchString("1AbCdEf_gH-0123456789"), "file ID")
+	assertions.False(fileIdRegexp.MatchString(""), "empty")
+}
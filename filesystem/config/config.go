@@ -4,6 +4,12 @@ import (
 	"context"
 	"net/http"
 	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/changes"
+	"github.com/pluto-org-co/gsuitefs/diskcache"
+	"github.com/pluto-org-co/gsuitefs/metastore"
+	"github.com/pluto-org-co/gsuitefs/organizers"
 )
 
 type HttpClientProviderFunc func(ctx context.Context, subject string) (client *http.Client)
@@ -13,6 +19,16 @@ type (
 		Active  bool
 		Trashed bool
 	}
+	IncludeSharedDrives struct {
+		Active  bool
+		Trashed bool
+		// List every shared drive of the organization instead of only the
+		// ones the administrator is a member of
+		DomainAdminAccess bool
+		// Skip the drives hidden from the default view, they are still
+		// reachable by ID
+		ExcludeHidden bool
+	}
 	IncludeUsers struct {
 		PersonalDrive *IncludeDrive
 		SharedFiles   bool
@@ -25,16 +41,136 @@ type (
 	}
 	Include struct {
 		Domains      *IncludeDomains
-		SharedDrives *IncludeDrive
+		SharedDrives *IncludeSharedDrives
+	}
+	Export struct {
+		// Ordered preferred export MIME types indexed by the
+		// application/vnd.google-apps.* type of the document
+		Formats map[string][]string
+		// Expose every export format of a document as a sibling file
+		AllFormats bool
 	}
 	Cache struct {
 		Path       string
 		Expiration time.Duration
+		// Interval between removals of expired listings
+		SweepInterval time.Duration
+		// Maximum entries of every listing cache, unlimited when zero
+		MaxEntries int
+		// Time expired listings are still served while refreshed in the
+		// background
+		Stale time.Duration
+		// Expiration of the lookups of missing files, not cached when zero
+		NegativeExpiration time.Duration
+		// Keep the listings under Path so they survive remounts
+		Persistent bool
+		// Age after which the persisted listings are removed. Expired ones
+		// are served while refreshed until then
+		PersistentMaxAge time.Duration
+		// Interval between polls of the Drive changes invalidating the
+		// listings, disabled when zero
+		ChangesInterval time.Duration
+		// Size in bytes of the blocks downloaded by streaming reads
+		BlockSize int64
+		// Blocks downloaded ahead on sequential reads
+		ReadAheadBlocks int64
+		// Download binary files completely on Open instead of streaming
+		FullDownload bool
+		// Maximum bytes used by the cached contents, unlimited when zero
+		MaxSize int64
+		// Bytes kept free in the disk holding the cache, ignored when zero
+		MinFreeSpace int64
+	}
+	// Kernel cache timeouts, the mount options are used when zero
+	Timeout struct {
+		Entry time.Duration
+		Attr  time.Duration
+	}
+	Timeouts struct {
+		// Used by the layers without their own timeouts
+		Default Timeout
+		// Kernel cache of missing names, shared by every layer
+		Negative time.Duration
+		Domains  Timeout
+		Users    Timeout
+		Folders  Timeout
+		Files    Timeout
 	}
 	Config struct {
 		Cache                  Cache
+		Export                 Export
 		AdministratorSubject   string
 		HttpClientProviderFunc HttpClientProviderFunc
 		Include                Include
+		Timeouts               Timeouts
+		// Tracks the cached contents, set by filesystem.New
+		CacheManager *diskcache.Manager
+		// Persisted listings, set by filesystem.New when Cache.Persistent
+		MetadataStore *metastore.Store
+		// Drive changes poller, set by filesystem.New when
+		// Cache.ChangesInterval is set
+		Changes *changes.Watcher
+		// Organizers impersonated to traverse the shared drives, set by
+		// filesystem.New when Include.SharedDrives.DomainAdminAccess is set
+		Organizers *organizers.Resolver
 	}
 )
+
+// SharedDriveSubject returns the user impersonated to traverse the shared
+// drive, always the administrator without domain admin access
+func (c *Config) SharedDriveSubject(ctx context.Context, driveId string) (subject string) {
+	if c.Organizers == nil {
+		return c.AdministratorSubject
+	}
+	return c.Organizers.Subject(ctx, driveId)
+}
+
+// EntryOut sets the timeouts of a looked up entry
+func (t Timeout) EntryOut(out *fuse.EntryOut) {
+	if t.Entry > 0 {
+		out.SetEntryTimeout(t.Entry)
+	}
+	if t.Attr > 0 {
+		out.SetAttrTimeout(t.Attr)
+	}
+}
+
+// AttrOut sets the timeout of the attributes
+func (t Timeout) AttrOut(out *fuse.AttrOut) {
+	if t.Attr > 0 {
+		out.SetTimeout(t.Attr)
+	}
+}
+
+// Export formats used for the document types missing in Export.Formats
+var DefaultExportFormats = map[string][]string{
+	"application/vnd.google-apps.document": {
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/pdf",
+	},
+	"application/vnd.google-apps.spreadsheet": {
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/pdf",
+	},
+	"application/vnd.google-apps.presentation": {
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/pdf",
+	},
+	"application/vnd.google-apps.drawing": {
+		"image/svg+xml",
+		"image/png",
+		"application/pdf",
+	},
+	"application/vnd.google-apps.form": {
+		"application/zip",
+	},
+	"application/vnd.google-apps.site": {
+		"text/plain",
+	},
+	"application/vnd.google-apps.script": {
+		"application/vnd.google-apps.script+json",
+	},
+	"application/vnd.google-apps.jam": {
+		"application/pdf",
+	},
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Run("Set", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		timeout := Timeout{Entry: 2 * time.Second, Attr: 3 * time.Second}
+		var entry fuse.EntryOut
+		timeout.EntryOut(&entry)
+		assertions.Equal(2*time.Second, entry.EntryTimeout(), "entry timeout")
+		assertions.Equal(3*time.Second, entry.AttrTimeout(), "entry attr timeout")
+
+		var attr fuse.AttrOut
+		timeout.AttrOut(&attr)
+		assertions.Equal(3*time.Second, attr.Timeout(), "attr timeout")
+	})
+	t.Run("Unset", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		// The mount options set by go-fuse are kept
+		var entry fuse.EntryOut
+		entry.SetEntryTimeout(time.Second)
+		entry.SetAttrTimeout(time.Second)
+		Timeout{}.EntryOut(&entry)
+		assertions.Equal(time.Second, entry.EntryTimeout(), "entry timeout")
+		assertions.Equal(time.Second, entry.AttrTimeout(), "entry attr timeout")
+
+		var attr fuse.AttrOut
+		attr.SetTimeout(time.Second)
+		Timeout{}.AttrOut(&attr)
+		assertions.Equal(time.Second, attr.Timeout(), "attr timeout")
+	})
+}
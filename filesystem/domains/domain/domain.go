@@ -7,6 +7,7 @@ import (
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/groups"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users"
 	admin "google.golang.org/api/admin/directory/v1"
 )
@@ -42,4 +43,11 @@ func (d *Domain) OnAdd(ctx context.Context) {
 	} else {
 		logger.Debug("Ignoring users")
 	}
+	if d.config.Include.Domains.Groups != nil {
+		logger.Debug("Including groups")
+		node := d.NewPersistentInode(ctx, groups.New(d.logger, d.config, d.domain), fs.StableAttr{Mode: syscall.S_IFDIR})
+		d.AddChild(groups.NodeName, node, false)
+	} else {
+		logger.Debug("Ignoring groups")
+	}
 }
@@ -0,0 +1,49 @@
+package group
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/groups/group/members"
+	admin "google.golang.org/api/admin/directory/v1"
+)
+
+const (
+	NodeName         = "group"
+	SettingsNodeName = "group.json"
+)
+
+type Group struct {
+	fs.Inode
+
+	domain *admin.Domains
+	group  *admin.Group
+	logger *slog.Logger
+	config *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, domain *admin.Domains, group *admin.Group) (g *Group) {
+	return &Group{
+		domain: domain,
+		group:  group,
+		logger: logger.With("inode", NodeName, "email", group.Email),
+		config: c,
+	}
+}
+
+var _ fs.NodeOnAdder = (*Group)(nil)
+
+func (g *Group) OnAdd(ctx context.Context) {
+	logger := g.logger.With("action", "OnAdd")
+
+	logger.Debug("Including members")
+	node := g.NewPersistentInode(ctx, members.New(g.logger, g.config, g.domain, g.group), fs.StableAttr{Mode: syscall.S_IFDIR})
+	g.AddChild(members.NodeName, node, false)
+
+	logger.Debug("Including settings")
+	node = g.NewPersistentInode(ctx, newSettings(g.logger, g.config, g.group), fs.StableAttr{Mode: syscall.S_IFREG})
+	g.AddChild(SettingsNodeName, node, false)
+}
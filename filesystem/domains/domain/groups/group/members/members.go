@@ -0,0 +1,169 @@
+package members
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "members"
+
+const ReaddirCacheKey = 0
+
+// Members lists the internal members of a group as symlinks to their
+// entries under the users and groups directories
+type Members struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, *admin.Member]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	domain       *admin.Domains
+	group        *admin.Group
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, domain *admin.Domains, group *admin.Group) (m *Members) {
+	return &Members{
+		domain: domain,
+		group:  group,
+		logger: logger.With("inode", NodeName),
+		config: c,
+	}
+}
+
+var (
+	_ fs.NodeLookuper  = (*Members)(nil)
+	_ fs.NodeReaddirer = (*Members)(nil)
+)
+
+// Relative path from the members directory to the member, empty for members
+// not mapped by the filesystem
+func (m *Members) linkTarget(member *admin.Member) (target string) {
+	var kind string
+	switch member.Type {
+	case "USER":
+		kind = "users"
+	case "GROUP":
+		kind = "groups"
+	default:
+		return ""
+	}
+
+	_, memberDomain, found := strings.Cut(member.Email, "@")
+	if !found {
+		return ""
+	}
+
+	// members -> group -> groups -> domain
+	if memberDomain == m.domain.DomainName {
+		return path.Join("..", "..", "..", kind, names.Encode(member.Email))
+	}
+	// members -> group -> groups -> domain -> domains
+	return path.Join("..", "..", "..", "..", names.Encode(memberDomain), kind, names.Encode(member.Email))
+}
+
+func (m *Members) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := m.logger.With("action", "Lookup", "name", name)
+
+	logger.Debug("Loading member")
+	memberEntry, err := m.lookupCache.LoadOrFetch(ctx, name, m.config.Cache.Expiration, func(ctx context.Context) (memberEntry *admin.Member, err error) {
+		client := m.config.HttpClientProviderFunc(ctx, m.config.AdministratorSubject)
+
+		logger.Debug("Preparing admin service")
+		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		memberEntry, err = adminSvc.Members.Get(m.group.Id, names.Decode(name)).Context(ctx).Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+		return memberEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve member information", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	target := m.linkTarget(memberEntry)
+	if target == "" {
+		logger.Debug("External member")
+		return nil, syscall.ENOENT
+	}
+
+	attr := fs.StableAttr{Mode: syscall.S_IFLNK, Ino: inodes.Ino(inodes.KindMember, m.group.Id, memberEntry.Id)}
+	if node = inodes.Reuse(&m.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	link := &fs.MemSymlink{Data: []byte(target)}
+	node = m.NewInode(ctx, link, attr)
+	return node, fs.OK
+}
+
+func (m *Members) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := m.logger.With("action", "Readdir")
+
+	logger.Debug("Loading member list")
+	dirEntries, err := m.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, m.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := m.config.HttpClientProviderFunc(ctx, m.config.AdministratorSubject)
+
+		logger.Debug("Preparing admin service")
+		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Retrieving member list")
+		err = adminSvc.Members.
+			List(m.group.Id).
+			Context(ctx).
+			Pages(ctx, func(ml *admin.Members) (err error) {
+				for _, member := range ml.Members {
+					if m.linkTarget(member) == "" {
+						logger.Debug("Ignoring external member", "email", member.Email, "type", member.Type)
+						continue
+					}
+					logger.Debug("Found member", "email", member.Email)
+					name := names.Encode(member.Email)
+					m.lookupCache.Store(name, member, m.config.Cache.Expiration)
+					dirEntries = append(dirEntries, fuse.DirEntry{
+						Mode: syscall.S_IFLNK,
+						Name: name,
+						Ino:  inodes.Ino(inodes.KindMember, m.group.Id, member.Id),
+					})
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("Failed to retrieve member list", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
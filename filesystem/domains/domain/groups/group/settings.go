@@ -0,0 +1,123 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+)
+
+const SettingsCacheKey = 0
+
+// Settings exposes the group as JSON. The group is fetched again once the
+// cached copy expires, so the contents follow the remote changes
+type Settings struct {
+	fs.Inode
+
+	settingsCache cache.Cache[int, *admin.Group]
+	group         *admin.Group
+	logger        *slog.Logger
+	config        *config.Config
+}
+
+// The group looked up by the parent is served until it expires. The logger
+// of the group is expected, it already identifies it
+func newSettings(logger *slog.Logger, c *config.Config, group *admin.Group) (s *Settings) {
+	s = &Settings{
+		group:  group,
+		logger: logger.With("inode", SettingsNodeName),
+		config: c,
+	}
+	s.settingsCache.Store(SettingsCacheKey, group, c.Cache.Expiration)
+	return s
+}
+
+var (
+	_ fs.NodeOpener    = (*Settings)(nil)
+	_ fs.NodeGetattrer = (*Settings)(nil)
+)
+
+func (s *Settings) contents(ctx context.Context, logger *slog.Logger) (contents []byte, err error) {
+	logger.Debug("Loading group")
+	group, err := s.settingsCache.LoadOrFetch(ctx, SettingsCacheKey, s.config.Cache.Expiration, func(ctx context.Context) (group *admin.Group, err error) {
+		client := s.config.HttpClientProviderFunc(ctx, s.config.AdministratorSubject)
+
+		logger.Debug("Preparing admin service")
+		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		group, err = adminSvc.Groups.Get(s.group.Id).Context(ctx).Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+		return group, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err = json.MarshalIndent(group, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal group settings: %w", err)
+	}
+	return append(contents, '\n'), nil
+}
+
+func (s *Settings) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
+	logger := s.logger.With("action", "Open")
+
+	contents, err := s.contents(ctx, logger)
+	if err != nil {
+		logger.Error("failed to retrieve group settings", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, 0, httputils.ToErrno(err)
+	}
+	return settingsHandle(contents), 0, fs.OK
+}
+
+func (s *Settings) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) (errno syscall.Errno) {
+	logger := s.logger.With("action", "Getattr")
+
+	s.config.Timeouts.Files.AttrOut(out)
+	if contents, ok := fh.(settingsHandle); ok {
+		logger.Debug("Using file handle")
+		out.Mode = syscall.S_IFREG | 0o444
+		out.Size = uint64(len(contents))
+		return fs.OK
+	}
+
+	contents, err := s.contents(ctx, logger)
+	if err != nil {
+		logger.Error("failed to retrieve group settings", "error-msg", err, "error-reason", httputils.Reason(err))
+		return httputils.ToErrno(err)
+	}
+	out.Mode = syscall.S_IFREG | 0o444
+	out.Size = uint64(len(contents))
+	return fs.OK
+}
+
+// Contents of the group when opened, reads never see a partial update
+type settingsHandle []byte
+
+var _ fs.FileReader = settingsHandle(nil)
+
+func (h settingsHandle) Read(ctx context.Context, dest []byte, off int64) (result fuse.ReadResult, errno syscall.Errno) {
+	if off >= int64(len(h)) {
+		return fuse.ReadResultData(nil), fs.OK
+	}
+	end := min(off+int64(len(dest)), int64(len(h)))
+	return fuse.ReadResultData(h[off:end]), fs.OK
+}
@@ -0,0 +1,124 @@
+package groups
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/groups/group"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "groups"
+
+const ReaddirCacheKey = 0
+
+type Groups struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, *admin.Group]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	domain       *admin.Domains
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, domain *admin.Domains) (g *Groups) {
+	return &Groups{logger: logger.With("inode", NodeName), config: c, domain: domain}
+}
+
+var (
+	_ fs.NodeLookuper  = (*Groups)(nil)
+	_ fs.NodeReaddirer = (*Groups)(nil)
+)
+
+func (g *Groups) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := g.logger.With("action", "Lookup", "name", name)
+
+	logger.Debug("Loading group")
+	groupEntry, err := g.lookupCache.LoadOrFetch(ctx, name, g.config.Cache.Expiration, func(ctx context.Context) (groupEntry *admin.Group, err error) {
+		client := g.config.HttpClientProviderFunc(ctx, g.config.AdministratorSubject)
+
+		logger.Debug("Preparing admin service")
+		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		groupEntry, err = adminSvc.Groups.Get(names.Decode(name)).Context(ctx).Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+		return groupEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve group information", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindGroup, groupEntry.Id)}
+	if node = inodes.Reuse(&g.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = g.NewInode(ctx, group.New(g.logger, g.config, g.domain, groupEntry), attr)
+	return node, fs.OK
+}
+
+func (g *Groups) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := g.logger.With("action", "Readdir")
+
+	logger.Debug("Loading group list")
+	dirEntries, err := g.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, g.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := g.config.HttpClientProviderFunc(ctx, g.config.AdministratorSubject)
+
+		logger.Debug("Preparing admin service")
+		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Retrieving group list")
+		err = adminSvc.Groups.
+			List().
+			Context(ctx).
+			Domain(g.domain.DomainName).
+			OrderBy("email").
+			Pages(ctx, func(gl *admin.Groups) (err error) {
+				for _, group := range gl.Groups {
+					logger.Debug("Found group", "email", group.Email)
+					name := names.Encode(group.Email)
+					g.lookupCache.Store(name, group, g.config.Cache.Expiration)
+					dirEntries = append(dirEntries, fuse.DirEntry{
+						Mode: syscall.S_IFDIR,
+						Name: name,
+						Ino:  inodes.Ino(inodes.KindGroup, group.Id),
+					})
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("Failed to retrieve group list", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
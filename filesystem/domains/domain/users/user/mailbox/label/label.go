@@ -0,0 +1,148 @@
+package label
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user/mailbox/message"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "label"
+
+const ReaddirCacheKey = 0
+
+// Label lists every message tagged with a Gmail label as a read-only file
+type Label struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, *gmail.Message]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	label        *gmail.Label
+	user         *admin.User
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, user *admin.User, label *gmail.Label) (l *Label) {
+	return &Label{
+		label:  label,
+		user:   user,
+		logger: logger.With("inode", NodeName, "label-name", label.Name, "label-id", label.Id),
+		config: c,
+	}
+}
+
+var (
+	_ fs.NodeLookuper  = (*Label)(nil)
+	_ fs.NodeReaddirer = (*Label)(nil)
+)
+
+func (l *Label) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := l.logger.With("action", "Lookup", "name", name)
+
+	messageId, ok := strings.CutSuffix(name, message.Extension)
+	if !ok || messageId == "" {
+		logger.Debug("Not a message filename")
+		return nil, syscall.ENOENT
+	}
+
+	logger.Debug("Loading message")
+	messageEntry, err := l.lookupCache.LoadOrFetch(ctx, name, l.config.Cache.Expiration, func(ctx context.Context) (messageEntry *gmail.Message, err error) {
+		client := l.config.HttpClientProviderFunc(ctx, l.user.PrimaryEmail)
+
+		logger.Debug("Preparing gmail service")
+		gmailSvc, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		messageEntry, err = gmailSvc.Users.Messages.
+			Get("me", messageId).
+			Format("minimal").
+			Context(ctx).
+			Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+
+		if !slices.Contains(messageEntry.LabelIds, l.label.Id) {
+			return nil, cache.Negative(syscall.ENOENT)
+		}
+		return messageEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve message information", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	out.Mode = message.Mode
+	attr := fs.StableAttr{Mode: syscall.S_IFREG, Ino: inodes.Ino(inodes.KindMessage, l.user.Id, messageEntry.Id)}
+	if node = inodes.Reuse(&l.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = l.NewInode(ctx, message.New(l.logger, l.config, l.user, messageEntry), attr)
+	return node, fs.OK
+}
+
+func (l *Label) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := l.logger.With("action", "Readdir")
+
+	logger.Debug("Loading message list")
+	dirEntries, err := l.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, l.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := l.config.HttpClientProviderFunc(ctx, l.user.PrimaryEmail)
+
+		logger.Debug("Preparing gmail service")
+		gmailSvc, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Pulling message list")
+		err = gmailSvc.Users.Messages.
+			List("me").
+			LabelIds(l.label.Id).
+			IncludeSpamTrash(true).
+			MaxResults(500).
+			Context(ctx).
+			Pages(ctx, func(ml *gmail.ListMessagesResponse) (err error) {
+				logger.Debug("Retrieving page", "page-length", len(ml.Messages))
+				for _, msg := range ml.Messages {
+					dirEntries = append(dirEntries, fuse.DirEntry{
+						Mode: syscall.S_IFREG,
+						Name: msg.Id + message.Extension,
+						Ino:  inodes.Ino(inodes.KindMessage, l.user.Id, msg.Id),
+					})
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve messages", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
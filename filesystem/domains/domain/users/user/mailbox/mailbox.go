@@ -0,0 +1,135 @@
+package mailbox
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user/mailbox/label"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "gmail"
+
+const ReaddirCacheKey = 0
+
+// Mailbox lists the Gmail labels of a user as directories
+type Mailbox struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, *gmail.Label]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	user         *admin.User
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, user *admin.User) (m *Mailbox) {
+	return &Mailbox{
+		user:   user,
+		logger: logger.With("inode", NodeName),
+		config: c,
+	}
+}
+
+var (
+	_ fs.NodeLookuper  = (*Mailbox)(nil)
+	_ fs.NodeReaddirer = (*Mailbox)(nil)
+)
+
+func (m *Mailbox) listLabels(ctx context.Context, logger *slog.Logger) (labels []*gmail.Label, err error) {
+	client := m.config.HttpClientProviderFunc(ctx, m.user.PrimaryEmail)
+
+	logger.Debug("Preparing gmail service")
+	gmailSvc, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Pulling label list")
+	labelList, err := gmailSvc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return labelList.Labels, nil
+}
+
+func (m *Mailbox) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := m.logger.With("action", "Lookup", "name", name)
+
+	logger.Debug("Loading label")
+	labelEntry, err := m.lookupCache.LoadOrFetch(ctx, name, m.config.Cache.Expiration, func(ctx context.Context) (labelEntry *gmail.Label, err error) {
+		// Labels can only be retrieved by ID, so the whole list is required
+		labels, err := m.listLabels(ctx, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range labels {
+			if names.Encode(l.Name) == name {
+				labelEntry = l
+				break
+			}
+		}
+
+		if labelEntry == nil {
+			return nil, cache.Negative(syscall.ENOENT)
+		}
+		return labelEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve labels", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindLabel, m.user.Id, labelEntry.Id)}
+	if node = inodes.Reuse(&m.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = m.NewInode(ctx, label.New(m.logger, m.config, m.user, labelEntry), attr)
+	return node, fs.OK
+}
+
+func (m *Mailbox) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := m.logger.With("action", "Readdir")
+
+	logger.Debug("Loading label list")
+	dirEntries, err := m.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, m.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		labels, err := m.listLabels(ctx, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		dirEntries = make([]fuse.DirEntry, 0, len(labels))
+		for _, l := range labels {
+			logger.Debug("Listing label", "label-name", l.Name)
+			name := names.Encode(l.Name)
+			m.lookupCache.Store(name, l, m.config.Cache.Expiration)
+			dirEntries = append(dirEntries, fuse.DirEntry{
+				Mode: syscall.S_IFDIR,
+				Name: name,
+				Ino:  inodes.Ino(inodes.KindLabel, m.user.Id, l.Id),
+			})
+		}
+
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve labels", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
@@ -0,0 +1,254 @@
+package message
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	"golang.org/x/sys/unix"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "message"
+
+// Extension appended to the message ID to build its filename
+const Extension = ".eml"
+
+// Mode of every message, whatever the mode of its cache file
+const Mode = syscall.S_IFREG | 0o444
+
+// Message exposes a Gmail message in its RFC 822 form
+type Message struct {
+	fs.Inode
+
+	message *gmail.Message
+	user    *admin.User
+	logger  *slog.Logger
+	config  *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, user *admin.User, message *gmail.Message) (m *Message) {
+	return &Message{
+		message: message,
+		user:    user,
+		logger:  logger.With("inode", NodeName, "message-id", message.Id),
+		config:  c,
+	}
+}
+
+var (
+	_ fs.NodeOpener    = (*Message)(nil)
+	_ fs.NodeReleaser  = (*Message)(nil)
+	_ fs.NodeGetattrer = (*Message)(nil)
+)
+
+// Local copy of the message
+func (m *Message) cacheFilename() (cacheFilename string) {
+	// Message IDs are only unique inside a mailbox
+	return path.Join(m.config.Cache.Path, "gmail-"+m.user.Id+"-"+m.message.Id+Extension)
+}
+
+func (m *Message) fileInfo() (cacheFilename string, modTime time.Time, cached bool, err error) {
+	cacheFilename = m.cacheFilename()
+	modTime = time.UnixMilli(m.message.InternalDate)
+
+	_, err = os.Stat(cacheFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cacheFilename, modTime, false, nil
+		}
+		return cacheFilename, modTime, false, fmt.Errorf("failed to retrieve file info: %w", err)
+	}
+
+	// Messages are immutable, an existing copy is always valid
+	return cacheFilename, modTime, true, nil
+}
+
+// Writes the message next to its final path and moves it into place once
+// synced, readers never see a partial message
+func writeMessage(cacheFilename string, contents []byte, modTime time.Time) (err error) {
+	file, err := os.CreateTemp(path.Dir(cacheFilename), path.Base(cacheFilename)+".*"+files.PartialSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	tmpFilename := file.Name()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpFilename)
+		}
+	}()
+
+	_, err = file.Write(contents)
+	if err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync message: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close message: %w", err)
+	}
+
+	err = os.Chtimes(tmpFilename, time.Now(), modTime)
+	if err != nil {
+		return fmt.Errorf("failed to change modify time to local cache: %w", err)
+	}
+
+	err = os.Rename(tmpFilename, cacheFilename)
+	if err != nil {
+		return fmt.Errorf("failed to move message into place: %w", err)
+	}
+	return nil
+}
+
+func (m *Message) downloadMessage(ctx context.Context, logger *slog.Logger) (cacheFilename string, err error) {
+	cacheFilename, modTime, cached, err := m.fileInfo()
+	if err != nil {
+		return cacheFilename, fmt.Errorf("failed to get message information: %w", err)
+	}
+
+	if cached {
+		logger.Debug("Message already cached")
+		return cacheFilename, nil
+	}
+
+	logger.Debug("Pulling from remote")
+
+	client := m.config.HttpClientProviderFunc(ctx, m.user.PrimaryEmail)
+
+	logger.Debug("Preparing gmail service")
+	gmailSvc, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare gmail service: %w", err)
+	}
+
+	logger.Debug("Downloading raw message")
+	rawMessage, err := gmailSvc.Users.Messages.
+		Get("me", m.message.Id).
+		Format("raw").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to download message: %w", err)
+	}
+
+	logger.Debug("Decoding contents")
+	contents, err := base64.URLEncoding.DecodeString(rawMessage.Raw)
+	if err != nil {
+		contents, err = base64.RawURLEncoding.DecodeString(rawMessage.Raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode raw message: %w", err)
+		}
+	}
+
+	logger.Debug("Saving message in cache")
+	err = writeMessage(cacheFilename, contents, modTime)
+	if err != nil {
+		return "", err
+	}
+
+	m.config.CacheManager.Update(cacheFilename)
+
+	logger.Debug("Message saved")
+	return cacheFilename, nil
+}
+
+func (m *Message) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
+	logger := m.logger.With("action", "Open")
+
+	logger.Debug("Protecting message from eviction")
+	key := m.cacheFilename()
+	m.config.CacheManager.Acquire(key)
+	defer func() {
+		if errno != fs.OK {
+			m.config.CacheManager.Release(key)
+		}
+	}()
+
+	filename, err := m.downloadMessage(ctx, logger)
+	if err != nil {
+		logger.Error("Failed to download message", "filename", filename, "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, 0, httputils.ToErrno(err)
+	}
+
+	logger.Debug("Openning file")
+	fd, err := unix.Open(filename, unix.O_RDONLY, 0)
+	if err != nil {
+		logger.Error("Failed to open file", "error-msg", err)
+		return nil, 0, httputils.ToErrno(err)
+	}
+
+	fh = fs.NewLoopbackFile(fd)
+	return fh, 0, fs.OK
+}
+
+func (m *Message) Release(ctx context.Context, fh fs.FileHandle) (errno syscall.Errno) {
+	logger := m.logger.With("action", "Release")
+
+	if fr, ok := fh.(fs.FileReleaser); ok {
+		errno = fr.Release(ctx)
+	}
+
+	logger.Debug("Allowing eviction of message")
+	m.config.CacheManager.Release(m.cacheFilename())
+	return errno
+}
+
+func (m *Message) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) (errno syscall.Errno) {
+	logger := m.logger.With("action", "Getattr")
+
+	if fh != nil {
+		logger.Debug("Checking file handle")
+		if fga, ok := fh.(fs.FileGetattrer); ok {
+			logger.Debug("Using file handle")
+			errno = fga.Getattr(ctx, out)
+			out.Mode = Mode
+			return errno
+		} else {
+			logger.Debug("File handle of wrong type")
+		}
+	}
+
+	filename, modTime, cached, err := m.fileInfo()
+	if err != nil {
+		logger.Error("failed to get message information", "error-msg", err)
+		return httputils.ToErrno(err)
+	}
+
+	var stat syscall.Stat_t
+	if cached {
+		err = syscall.Lstat(filename, &stat)
+		if err != nil {
+			logger.Error("Failed to get file Lstat", "error-msg", err)
+			return httputils.ToErrno(err)
+		}
+	} else {
+		stat = syscall.Stat_t{
+			Mode: Mode,
+			Size: m.message.SizeEstimate,
+			Atim: syscall.NsecToTimespec(modTime.UnixNano()),
+			Mtim: syscall.NsecToTimespec(modTime.UnixNano()),
+			Ctim: syscall.NsecToTimespec(modTime.UnixNano()),
+		}
+	}
+
+	out.FromStat(&stat)
+	// The cache file is writable by the mount, messages never are
+	out.Mode = Mode
+	return fs.OK
+}
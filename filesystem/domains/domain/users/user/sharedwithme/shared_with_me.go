@@ -0,0 +1,161 @@
+package sharedwithme
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/directory"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "shared-with-me"
+
+const ReaddirCacheKey = 0
+
+// SharedWithMe groups the files shared with a user by the email of their owner
+type SharedWithMe struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, string]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	user         *admin.User
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config, user *admin.User) (s *SharedWithMe) {
+	return &SharedWithMe{
+		user:   user,
+		logger: logger.With("inode", NodeName),
+		config: c,
+	}
+}
+
+var (
+	_ fs.NodeLookuper  = (*SharedWithMe)(nil)
+	_ fs.NodeReaddirer = (*SharedWithMe)(nil)
+)
+
+func (s *SharedWithMe) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := s.logger.With("action", "Lookup", "name", name)
+
+	logger.Debug("Loading owner")
+	owner, err := s.lookupCache.LoadOrFetch(ctx, name, s.config.Cache.Expiration, func(ctx context.Context) (owner string, err error) {
+		client := s.config.HttpClientProviderFunc(ctx, s.user.PrimaryEmail)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return "", err
+		}
+
+		fl, err := driveSvc.Files.
+			List().
+			Corpora("user").
+			Fields("files(id)").
+			Q(fmt.Sprintf("trashed=false and sharedWithMe=true and '%s' in owners", directory.EscapeQuery(names.Decode(name)))).
+			PageSize(1).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return "", err
+		}
+
+		if len(fl.Files) == 0 {
+			return "", cache.Negative(syscall.ENOENT)
+		}
+
+		owner = names.Decode(name)
+		return owner, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve owner", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindOwner, s.user.Id, owner)}
+	if node = inodes.Reuse(&s.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	cfg := directory.Config{
+		Logger:   s.logger,
+		Config:   s.config,
+		User:     s.user,
+		SharedBy: owner,
+	}
+	node = s.NewInode(ctx, directory.New(&cfg), attr)
+	return node, fs.OK
+}
+
+func (s *SharedWithMe) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := s.logger.With("action", "Readdir")
+
+	logger.Debug("Loading owner list")
+	dirEntries, err := s.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, s.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := s.config.HttpClientProviderFunc(ctx, s.user.PrimaryEmail)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Pulling owners list")
+		var owners = map[string]struct{}{}
+		err = driveSvc.Files.
+			List().
+			Corpora("user").
+			Fields("nextPageToken,files(owners(emailAddress))").
+			Q("trashed=false and sharedWithMe=true").
+			PageSize(1_000).
+			Context(ctx).
+			Pages(ctx, func(fl *drive.FileList) (err error) {
+				logger.Debug("Retrieving page", "page-length", len(fl.Files))
+				for _, file := range fl.Files {
+					for _, owner := range file.Owners {
+						if owner.EmailAddress == "" {
+							continue
+						}
+						if _, found := owners[owner.EmailAddress]; found {
+							continue
+						}
+						logger.Debug("Found owner", "owner", owner.EmailAddress)
+						owners[owner.EmailAddress] = struct{}{}
+						name := names.Encode(owner.EmailAddress)
+						s.lookupCache.Store(name, owner.EmailAddress, s.config.Cache.Expiration)
+						dirEntries = append(dirEntries, fuse.DirEntry{
+							Mode: syscall.S_IFDIR,
+							Name: name,
+							Ino:  inodes.Ino(inodes.KindOwner, s.user.Id, owner.EmailAddress),
+						})
+					}
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve shared files", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
@@ -7,7 +7,9 @@ import (
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user/mailbox"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user/personaldrive"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user/sharedwithme"
 	admin "google.golang.org/api/admin/directory/v1"
 )
 
@@ -38,6 +40,18 @@ func (u *User) OnAdd(ctx context.Context) {
 	} else {
 		logger.Debug("Ignoring personal drive")
 	}
-	// TODO: Shared drives
-	// TODO: Gmail
+	if u.config.Include.Domains.Users.SharedFiles {
+		logger.Debug("Including shared with me")
+		node := u.NewPersistentInode(ctx, sharedwithme.New(u.logger, u.config, u.user), fs.StableAttr{Mode: syscall.S_IFDIR})
+		u.AddChild(sharedwithme.NodeName, node, false)
+	} else {
+		logger.Debug("Ignoring shared with me")
+	}
+	if u.config.Include.Domains.Users.Gmail {
+		logger.Debug("Including gmail")
+		node := u.NewPersistentInode(ctx, mailbox.New(u.logger, u.config, u.user), fs.StableAttr{Mode: syscall.S_IFDIR})
+		u.AddChild(mailbox.NodeName, node, false)
+	} else {
+		logger.Debug("Ignoring gmail")
+	}
 }
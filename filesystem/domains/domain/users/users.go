@@ -10,6 +10,9 @@ import (
 	"github.com/pluto-org-co/gsuitefs/cache"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain/users/user"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/option"
 )
@@ -29,7 +32,10 @@ type Users struct {
 }
 
 func New(logger *slog.Logger, c *config.Config, domain *admin.Domains) (u *Users) {
-	return &Users{logger: logger.With("inode", NodeName), config: c, domain: domain}
+	u = &Users{logger: logger.With("inode", NodeName), config: c, domain: domain}
+	u.lookupCache.Persist(c.MetadataStore, "users/"+domain.DomainName+"/lookup")
+	u.readdirCache.Persist(c.MetadataStore, "users/"+domain.DomainName+"/readdir")
+	return u
 }
 
 var (
@@ -40,45 +46,52 @@ var (
 func (u *Users) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
 	logger := u.logger.With("action", "Lookup")
 
-	logger.Debug("Checking cache")
-	userEntry, found := u.lookupCache.Load(name)
-	if !found {
+	logger.Debug("Loading user")
+	userEntry, err := u.lookupCache.LoadOrFetch(ctx, name, u.config.Cache.Expiration, func(ctx context.Context) (userEntry *admin.User, err error) {
 		client := u.config.HttpClientProviderFunc(ctx, u.config.AdministratorSubject)
 
 		logger.Debug("Preparing admin service")
 		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
-		userEntry, err = adminSvc.Users.Get(name).Context(ctx).Do()
+		userEntry, err = adminSvc.Users.Get(names.Decode(name)).Context(ctx).Do()
 		if err != nil {
-			logger.Error("failed to retrieve domain information", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
 		}
-		logger.Debug("Storing in cache")
-		u.lookupCache.Store(name, userEntry, u.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		return userEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve user information", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
-	node = u.NewInode(ctx, user.New(u.logger, u.config, userEntry), fs.StableAttr{Mode: syscall.S_IFDIR})
+	u.config.Timeouts.Users.EntryOut(out)
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindUser, userEntry.Id)}
+	if node = inodes.Reuse(&u.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = u.NewInode(ctx, user.New(u.logger, u.config, userEntry), attr)
 	return node, fs.OK
 }
 
 func (u *Users) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
 	logger := u.logger.With("action", "Readdir")
 
-	logger.Debug("Checking cache")
-	dirEntries, found := u.readdirCache.Load(ReaddirCacheKey)
-	if !found {
+	logger.Debug("Loading user list")
+	dirEntries, err := u.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, u.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
 		client := u.config.HttpClientProviderFunc(ctx, u.config.AdministratorSubject)
 
 		logger.Debug("Preparing admin service")
 		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			return
+			return nil, err
 		}
 
 		logger.Debug("Retrieving user list")
@@ -90,23 +103,24 @@ func (u *Users) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Err
 			Pages(ctx, func(ul *admin.Users) (err error) {
 				for _, user := range ul.Users {
 					logger.Debug("Found username", "primary-email", user.PrimaryEmail)
-					u.lookupCache.Store(user.PrimaryEmail, user, u.config.Cache.Expiration)
+					name := names.Encode(user.PrimaryEmail)
+					u.lookupCache.Store(name, user, u.config.Cache.Expiration)
 					dirEntries = append(dirEntries, fuse.DirEntry{
 						Mode: syscall.S_IFDIR,
-						Name: user.PrimaryEmail,
+						Name: name,
+						Ino:  inodes.Ino(inodes.KindUser, user.Id),
 					})
 				}
 				return nil
 			})
 		if err != nil {
-			logger.Error("Failed to retrieve user list", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
-
-		logger.Debug("Storing in cache")
-		u.readdirCache.Store(ReaddirCacheKey, dirEntries, u.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("Failed to retrieve user list", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
 	ds = fs.NewListDirStream(dirEntries)
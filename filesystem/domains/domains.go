@@ -10,6 +10,9 @@ import (
 	"github.com/pluto-org-co/gsuitefs/cache"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains/domain"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/option"
 )
@@ -28,7 +31,10 @@ type Domains struct {
 }
 
 func New(logger *slog.Logger, c *config.Config) (d *Domains) {
-	return &Domains{logger: logger.With("inode", NodeName), config: c}
+	d = &Domains{logger: logger.With("inode", NodeName), config: c}
+	d.lookupCache.Persist(c.MetadataStore, "domains/lookup")
+	d.readdirCache.Persist(c.MetadataStore, "domains/readdir")
+	return d
 }
 
 var (
@@ -39,46 +45,52 @@ var (
 func (d *Domains) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
 	logger := d.logger.With("action", "Lookup", "name", name)
 
-	logger.Debug("Checking cache")
-	domainEntry, found := d.lookupCache.Load(name)
-	if !found {
+	logger.Debug("Loading domain")
+	domainEntry, err := d.lookupCache.LoadOrFetch(ctx, name, d.config.Cache.Expiration, func(ctx context.Context) (domainEntry *admin.Domains, err error) {
 		client := d.config.HttpClientProviderFunc(ctx, d.config.AdministratorSubject)
 
 		logger.Debug("Preparing admin service")
 		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
-		domainEntry, err = adminSvc.Domains.Get("my_customer", name).Context(ctx).Do()
+		domainEntry, err = adminSvc.Domains.Get("my_customer", names.Decode(name)).Context(ctx).Do()
 		if err != nil {
-			logger.Error("failed to retrieve domain information", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
 		}
-		logger.Debug("Storing in cache")
-		d.lookupCache.Store(name, domainEntry, d.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		return domainEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve domain information", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
-	node = d.NewInode(ctx, domain.New(d.logger, d.config, domainEntry), fs.StableAttr{Mode: syscall.S_IFDIR})
+	d.config.Timeouts.Domains.EntryOut(out)
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindDomain, domainEntry.DomainName)}
+	if node = inodes.Reuse(&d.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = d.NewInode(ctx, domain.New(d.logger, d.config, domainEntry), attr)
 	return node, fs.OK
 }
 
 func (d *Domains) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
 	logger := d.logger.With("action", "Readdir")
 
-	logger.Debug("Checking cache")
-	dirEntries, found := d.readdirCache.Load(ReaddirCacheKey)
-	if !found {
+	logger.Debug("Loading domain list")
+	dirEntries, err := d.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, d.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
 		client := d.config.HttpClientProviderFunc(ctx, d.config.AdministratorSubject)
 
 		logger.Debug("Preparing admin service")
 		adminSvc, err := admin.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
 		logger.Debug("Pulling Domain list")
@@ -87,23 +99,25 @@ func (d *Domains) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.E
 			Context(ctx).
 			Do()
 		if err != nil {
-			logger.Error("failed to retrieve domains", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
 		dirEntries = make([]fuse.DirEntry, 0, len(domainList.Domains))
 		for _, domain := range domainList.Domains {
 			logger.Debug("Listing domain", "domain-name", domain.DomainName)
-			d.lookupCache.Store(domain.DomainName, domain, d.config.Cache.Expiration)
+			name := names.Encode(domain.DomainName)
+			d.lookupCache.Store(name, domain, d.config.Cache.Expiration)
 			dirEntries = append(dirEntries, fuse.DirEntry{
 				Mode: syscall.S_IFDIR,
-				Name: domain.DomainName,
+				Name: name,
+				Ino:  inodes.Ino(inodes.KindDomain, domain.DomainName),
 			})
 		}
-		logger.Debug("Storing in cache")
-		d.readdirCache.Store(ReaddirCacheKey, dirEntries, d.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve domains", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
 	ds = fs.NewListDirStream(dirEntries)
@@ -0,0 +1,180 @@
+package directory
+
+import (
+	"log/slog"
+	"slices"
+	"syscall"
+
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/changes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"google.golang.org/api/drive/v3"
+)
+
+var _ changes.Invalidator = (*Directory)(nil)
+
+// Starts watching the changes of the drive listed by the directory
+func (d *Directory) watch() {
+	if d.drive != nil {
+		d.config.Changes.Watch(d.config.AdministratorSubject, d.drive.Id, d.driveNamespace())
+	} else {
+		d.config.Changes.Watch(d.user.PrimaryEmail, "", d.driveNamespace())
+	}
+	switch {
+	case d.directory != nil:
+		d.watchFolder(d.directory.Id)
+	case d.drive != nil:
+		// The root folder of a shared drive shares its ID
+		d.watchFolder(d.drive.Id)
+	}
+}
+
+// Receives the changes of the files inside the folder listed by the directory
+func (d *Directory) watchFolder(id string) {
+	d.idsMu.Lock()
+	defer d.idsMu.Unlock()
+
+	if _, found := d.ids[id]; found {
+		return
+	}
+	d.ids[id] = struct{}{}
+	changes.Register(d.config.Changes, id, d)
+}
+
+// Receives the changes of the listed file and of the files moved into any
+// of its parents, which is how the root folders learn their own ID. The files
+// shared with the user are listed by owner, their parents are unrelated
+func (d *Directory) register(file *drive.File) {
+	changes.Register(d.config.Changes, file.Id, d)
+	if d.directory == nil && d.drive == nil && d.sharedBy != "" {
+		return
+	}
+	for _, parent := range file.Parents {
+		d.watchFolder(parent)
+	}
+}
+
+// Reports if the changed file is listed by the directory after the change
+func (d *Directory) contains(file *drive.File) (ok bool) {
+	if file == nil || file.Trashed != d.trashed {
+		return false
+	}
+	if d.directory == nil && d.drive == nil && d.sharedBy != "" {
+		// Listed by owner instead of by folder
+		return file.SharedWithMeTime != "" && slices.ContainsFunc(file.Owners, func(owner *drive.User) bool {
+			return owner.EmailAddress == d.sharedBy
+		})
+	}
+
+	d.idsMu.Lock()
+	defer d.idsMu.Unlock()
+	for _, parent := range file.Parents {
+		if _, found := d.ids[parent]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports if the change adds, removes, renames or moves an entry of the
+// directory. The entries are the ones cached for the changed file, a change of
+// a file inside one of them doesn't affect the listing
+func (d *Directory) listingChanged(change *drive.Change, listed []*entry, children map[string]struct{}) (changed bool) {
+	inside := !change.Removed && d.contains(change.File)
+	if len(listed) == 0 {
+		if inside {
+			return true
+		}
+		for _, parent := range change.File.Parents {
+			if _, found := children[parent]; found {
+				return false
+			}
+		}
+		// Registered while listed, the entry is no longer cached
+		return true
+	}
+	if !inside {
+		return true
+	}
+	for _, e := range listed {
+		if e.file.Name != change.File.Name || e.file.MimeType != change.File.MimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// Invalidate drops the entries of the changed file. The listing is only
+// dropped, and the kernel notified, when the change alters it
+func (d *Directory) Invalidate(change *drive.Change) {
+	logger := d.logger.With("action", "Invalidate", "file-id", change.FileId)
+	if change.File == nil {
+		change.File = &drive.File{Id: change.FileId}
+	}
+
+	var (
+		listed   []*entry
+		children = map[string]struct{}{}
+	)
+	d.lookupCache.Range(func(e *cache.Entry[string, *entry]) bool {
+		if e.Err == nil {
+			children[e.Value.file.Id] = struct{}{}
+			if e.Value.file.Id == change.FileId {
+				listed = append(listed, e.Value)
+			}
+		}
+		return true
+	})
+	changed := d.listingChanged(change, listed, children)
+	if len(listed) == 0 && !changed {
+		return
+	}
+
+	removed := d.lookupCache.DeleteFunc(func(e *cache.Entry[string, *entry]) bool {
+		if e.Err != nil {
+			// Missing names the file may now be listed with
+			return changed && slices.Contains(remoteCandidates(e.Key), change.File.Name)
+		}
+		return e.Value.file.Id == change.FileId
+	})
+
+	if len(listed) > 0 {
+		err := files.InvalidateContents(d.config.Cache.Path, change.FileId)
+		if err != nil {
+			logger.Warn("Failed to invalidate contents", "error-msg", err)
+		}
+	}
+
+	for _, name := range removed {
+		logger.Debug("Invalidating entry", "name", names.Decode(name))
+		if child := d.GetChild(name); child != nil {
+			child.NotifyContent(0, 0)
+		}
+		d.NotifyEntry(name)
+	}
+	if changed {
+		logger.Debug("Invalidating listing")
+		d.readdirCache.Delete(ReaddirCacheKey)
+		d.NotifyContent(0, 0)
+	}
+}
+
+// Notifies the kernel when the child known by the name of the entry is an
+// older version of the file. The kernel holds the directory lock during
+// lookups and listings, so the notifications are sent asynchronously
+func (d *Directory) invalidateVersion(logger *slog.Logger, e *entry) {
+	if e.mode() != syscall.S_IFREG {
+		return
+	}
+	child := d.GetChild(e.name)
+	if child == nil || child.StableAttr() == e.stableAttr() {
+		return
+	}
+
+	logger.Debug("Invalidating previous version", "name", names.Decode(e.name), "version", e.file.Version)
+	go func() {
+		child.NotifyContent(0, 0)
+		d.NotifyEntry(e.name)
+	}()
+}
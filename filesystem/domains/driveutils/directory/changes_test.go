@@ -0,0 +1,83 @@
+package directory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+func TestDirectory_listingChanged(t *testing.T) {
+	report := &drive.File{Id: "report00000", Name: "report.pdf", MimeType: "application/pdf", Parents: []string{"folder00000"}}
+	child := &drive.File{Id: "child000000", Name: "child", MimeType: FolderMimeType, Parents: []string{"folder00000"}}
+	listed := []*entry{{name: "report.pdf", file: report}}
+	children := map[string]struct{}{report.Id: {}, child.Id: {}}
+
+	changed := func(file *drive.File) (change *drive.Change) {
+		return &drive.Change{FileId: file.Id, File: file}
+	}
+	moved := *report
+	moved.Parents = []string{"elsewhere00"}
+	renamed := *report
+	renamed.Name = "renamed.pdf"
+	trashed := *report
+	trashed.Trashed = true
+	edited := *report
+	edited.Version = 8
+
+	type Test struct {
+		Name    string
+		Change  *drive.Change
+		Listed  []*entry
+		Changed bool
+	}
+	tests := []Test{
+		{Name: "Content edit", Change: changed(&edited), Listed: listed},
+		{Name: "Renamed", Change: changed(&renamed), Listed: listed, Changed: true},
+		{Name: "Moved out", Change: changed(&moved), Listed: listed, Changed: true},
+		{Name: "Trashed", Change: changed(&trashed), Listed: listed, Changed: true},
+		{Name: "Removed", Change: &drive.Change{FileId: report.Id, Removed: true, File: &drive.File{Id: report.Id}}, Listed: listed, Changed: true},
+		{Name: "Added", Change: changed(&drive.File{Id: "added000000", Name: "added.pdf", Parents: []string{"folder00000"}}), Changed: true},
+		{Name: "Inside a child", Change: changed(&drive.File{Id: "grandchild0", Name: "grandchild.pdf", Parents: []string{child.Id}})},
+		{Name: "Evicted entry", Change: changed(&moved), Changed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			d := &Directory{
+				directory: &drive.File{Id: "folder00000"},
+				ids:       map[string]struct{}{"folder00000": {}},
+			}
+			assertions.Equal(test.Changed, d.listingChanged(test.Change, test.Listed, children), "changed")
+		})
+	}
+}
+
+func TestDirectory_contains(t *testing.T) {
+	t.Run("Shared with me", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		d := &Directory{sharedBy: "owner@example.com", ids: map[string]struct{}{}}
+		shared := &drive.File{
+			Id:               "shared00000",
+			SharedWithMeTime: "2024-01-01T00:00:00Z",
+			Owners:           []*drive.User{{EmailAddress: "owner@example.com"}},
+			Parents:          []string{"unrelated00"},
+		}
+		assertions.True(d.contains(shared), "shared by the owner")
+
+		other := *shared
+		other.Owners = []*drive.User{{EmailAddress: "other@example.com"}}
+		assertions.False(d.contains(&other), "shared by another owner")
+	})
+	t.Run("Trashed", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		d := &Directory{trashed: true, ids: map[string]struct{}{"folder00000": {}}}
+		file := &drive.File{Id: "file0000000", Parents: []string{"folder00000"}}
+		assertions.False(d.contains(file), "not trashed")
+		file.Trashed = true
+		assertions.True(d.contains(file), "trashed")
+	})
+}
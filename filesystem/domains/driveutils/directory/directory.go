@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,6 +16,11 @@ import (
 	"github.com/pluto-org-co/gsuitefs/cache"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/revisions"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/xattrs"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
@@ -28,19 +35,32 @@ type Config struct {
 	Drive     *drive.Drive
 	Trashed   bool
 	Directory *drive.File
+	// Lists the files shared with the user by this owner instead of the
+	// personal drive root. Only used when Directory is nil
+	SharedBy string
 }
 
 const ReaddirCacheKey = 0
 
+const FolderMimeType = "application/vnd.google-apps.folder"
+
+// Fields requested for every listed file
+const FileFields = "id,version,name,parents,fullFileExtension,mimeType,size,sha256Checksum,modifiedTime,createdTime,exportLinks,shortcutDetails(targetId,targetMimeType)," + xattrs.Fields
+
 type Directory struct {
 	fs.Inode
 
-	lookupCache  cache.Cache[string, *drive.File]
+	lookupCache  cache.Cache[string, *entry]
 	readdirCache cache.Cache[int, []fuse.DirEntry]
 
 	// Leave empty for root
 	trashed   bool
 	directory *drive.File
+	sharedBy  string
+
+	// IDs of the folder listed, the roots learn them from their entries
+	idsMu sync.Mutex
+	ids   map[string]struct{}
 
 	drive  *drive.Drive
 	user   *admin.User
@@ -61,6 +81,16 @@ func New(cfg *Config) (p *Directory) {
 			dirName = cfg.Directory.Name
 		}
 		logger = logger.With("mode", "personal-drive", "directory-name", dirName, "directory-id", dirId)
+	case cfg.User != nil && cfg.SharedBy != "":
+		var dirId, dirName string
+		if cfg.Directory == nil {
+			dirId = "shared-with-me"
+			dirName = cfg.SharedBy
+		} else {
+			dirId = cfg.Directory.Id
+			dirName = cfg.Directory.Name
+		}
+		logger = logger.With("mode", "shared-with-me", "directory-name", dirName, "directory-id", dirId)
 	case cfg.User != nil:
 		var dirId, dirName string
 		if cfg.Directory == nil {
@@ -73,48 +103,89 @@ func New(cfg *Config) (p *Directory) {
 		logger = logger.With("mode", "personal-drive", "directory-name", dirName, "directory-id", dirId)
 	}
 
-	return &Directory{
+	p = &Directory{
 		logger:    logger,
 		config:    cfg.Config,
 		user:      cfg.User,
 		drive:     cfg.Drive,
 		trashed:   cfg.Trashed,
 		directory: cfg.Directory,
+		sharedBy:  cfg.SharedBy,
+		ids:       make(map[string]struct{}),
 	}
+	p.lookupCache.Persist(cfg.Config.MetadataStore, p.namespace()+"/lookup")
+	p.readdirCache.Persist(cfg.Config.MetadataStore, p.namespace()+"/readdir")
+	p.watch()
+	return p
+}
+
+// Identifies every listing of the drive in the metadata store
+func (d *Directory) driveNamespace() (namespace string) {
+	if d.drive != nil {
+		return "directory/drive/" + d.drive.Id
+	}
+	return "directory/user/" + d.user.Id
+}
+
+// Identifies the listing of the directory in the metadata store
+func (d *Directory) namespace() (namespace string) {
+	namespace = d.driveNamespace()
+	if d.drive == nil && d.sharedBy != "" {
+		namespace += "/shared-by/" + d.sharedBy
+	}
+	if d.trashed {
+		namespace += "/trashed"
+	}
+	if d.directory != nil {
+		namespace += "/" + d.directory.Id
+	}
+	return namespace
 }
 
 var (
-	_ fs.NodeLookuper  = (*Directory)(nil)
-	_ fs.NodeReaddirer = (*Directory)(nil)
-	_ fs.NodeGetattrer = (*Directory)(nil)
+	_ fs.NodeLookuper    = (*Directory)(nil)
+	_ fs.NodeReaddirer   = (*Directory)(nil)
+	_ fs.NodeGetattrer   = (*Directory)(nil)
+	_ fs.NodeGetxattrer  = (*Directory)(nil)
+	_ fs.NodeListxattrer = (*Directory)(nil)
 )
 
 func (d *Directory) HttpClient(ctx context.Context) (client *http.Client) {
 	if d.drive != nil {
-		return d.config.HttpClientProviderFunc(ctx, d.config.AdministratorSubject)
+		return d.config.HttpClientProviderFunc(ctx, d.config.SharedDriveSubject(ctx, d.drive.Id))
 	}
 	return d.config.HttpClientProviderFunc(ctx, d.user.PrimaryEmail)
 }
 
+var queryReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// Escapes a value used inside a quoted string of a Drive query
+func EscapeQuery(value string) (escaped string) {
+	return queryReplacer.Replace(value)
+}
+
 func (d *Directory) ListCall(svc *drive.Service, name string) (call *drive.FilesListCall, err error) {
 	call = svc.Files.List()
 	switch {
 	case d.user != nil:
-		var dirId string
-		if d.directory == nil {
-			dirId = "root"
-		} else {
-			dirId = d.directory.Id
-		}
-
 		call = call.
 			Corpora("user").
-			Fields("nextPageToken,files(id,name,fullFileExtension,mimeType,size,modifiedTime,createdTime,exportLinks)").
+			Fields("nextPageToken,files(" + FileFields + ")").
 			OrderBy("name")
+
+		var query string
+		switch {
+		case d.directory == nil && d.sharedBy != "":
+			query = fmt.Sprintf("trashed=%t and sharedWithMe=true and '%s' in owners", d.trashed, EscapeQuery(d.sharedBy))
+		case d.directory == nil:
+			query = fmt.Sprintf("trashed=%t and 'root' in parents", d.trashed)
+		default:
+			query = fmt.Sprintf("trashed=%t and '%s' in parents", d.trashed, d.directory.Id)
+		}
 		if name == "" {
-			return call.Q(fmt.Sprintf("trashed=%t and '%s' in parents", d.trashed, dirId)), nil
+			return call.Q(query), nil
 		}
-		return call.Q(fmt.Sprintf("trashed=%t and '%s' in parents and name = '%s'", d.trashed, dirId, name)), nil
+		return call.Q(fmt.Sprintf("%s and name = '%s'", query, EscapeQuery(name))), nil
 	case d.drive != nil:
 		var dirId string
 		if d.directory == nil {
@@ -125,7 +196,7 @@ func (d *Directory) ListCall(svc *drive.Service, name string) (call *drive.Files
 
 		call = call.
 			Corpora("drive").
-			Fields("nextPageToken,files(id,name,fullFileExtension,mimeType,size,modifiedTime,createdTime,exportLinks)").
+			Fields("nextPageToken,files(" + FileFields + ")").
 			OrderBy("name").
 			IncludeTeamDriveItems(true).
 			IncludeItemsFromAllDrives(true).
@@ -135,54 +206,152 @@ func (d *Directory) ListCall(svc *drive.Service, name string) (call *drive.Files
 		if name == "" {
 			return call.Q(fmt.Sprintf("trashed=%t and '%s' in parents", d.trashed, dirId)), nil
 		}
-		return call.Q(fmt.Sprintf("trashed=%t and '%s' in parents and name = '%s'", d.trashed, dirId, name)), nil
+		return call.Q(fmt.Sprintf("trashed=%t and '%s' in parents and name = '%s'", d.trashed, dirId, EscapeQuery(name))), nil
 	default:
 		return nil, errors.New("incomplete directory definition: expecting user or drive to be passed")
 	}
 }
 
-func (d *Directory) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
-	logger := d.logger.With("action", "Readdir")
+// Returns every file in the directory with the exact remote name
+func (d *Directory) findFiles(ctx context.Context, svc *drive.Service, name string) (files []*drive.File, err error) {
+	call, err := d.ListCall(svc, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare list call: %w", err)
+	}
+
+	err = call.
+		Context(ctx).
+		PageSize(100).
+		Pages(ctx, func(fl *drive.FileList) (err error) {
+			files = append(files, fl.Files...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
 
-	logger.Debug("Checking cache")
-	file, found := d.lookupCache.Load(name)
-	if !found {
+// Resolves the entry name into the remote object
+func (d *Directory) resolve(ctx context.Context, logger *slog.Logger, name string) (dirEntry *entry, errno syscall.Errno) {
+	logger.Debug("Loading entry")
+	dirEntry, err := d.lookupCache.LoadOrFetch(ctx, name, d.config.Cache.Expiration, func(ctx context.Context) (dirEntry *entry, err error) {
 		client := d.HttpClient(ctx)
 
 		logger.Debug("Preparing drive service")
 		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
-		logger.Debug("Pulling file list")
-		call, err := d.ListCall(driveSvc, name)
-		if err != nil {
-			logger.Error("Failed to prepare list call", "error-msg", err)
-			return nil, fs.ToErrno(err)
+		// The entry name may be decorated with an export extension or an
+		// ID suffix, so every remote name it could come from is pulled to
+		// know which file claims it
+		var candidates []*drive.File
+		for _, candidate := range remoteCandidates(name) {
+			logger.Debug("Pulling file list", "remote-name", candidate)
+			group, err := d.findFiles(ctx, driveSvc, candidate)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, group...)
 		}
 
-		fl, err := call.Context(ctx).PageSize(10).Do()
-		if err != nil {
-			logger.Error("Failed to pull file list", "error-msg", err)
-			return nil, fs.ToErrno(err)
+		for _, e := range d.groupEntries(candidates) {
+			if e.name == name {
+				d.register(e.file)
+				return e, nil
+			}
+		}
+
+		if names.IsTruncated(name) {
+			// Truncated names can't be mapped back to the remote name
+			logger.Debug("Listing directory to resolve truncated name")
+			_, err = d.loadEntries(ctx, logger)
+			if err != nil {
+				return nil, err
+			}
+			if dirEntry, found := d.lookupCache.Load(name); found {
+				return dirEntry, nil
+			}
 		}
 
-		if len(fl.Files) == 0 {
-			logger.Error("File not found")
-			return nil, syscall.ENOENT
+		logger.Debug("File not found")
+		return nil, cache.Negative(syscall.ENOENT)
+	})
+	if err != nil {
+		logger.Error("failed to resolve file", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+	return dirEntry, fs.OK
+}
+
+// Returns the revisions directory of the file listed with the name
+func (d *Directory) lookupRevisions(ctx context.Context, logger *slog.Logger, name, filename string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	dirEntry, errno := d.resolve(ctx, logger, filename)
+	if errno != fs.OK {
+		return nil, errno
+	}
+	if dirEntry.mode() != syscall.S_IFREG {
+		return nil, syscall.ENOENT
+	}
+	d.config.Timeouts.Folders.EntryOut(out)
+
+	attr := fs.StableAttr{
+		Mode: syscall.S_IFDIR,
+		Ino:  inodes.Ino(inodes.KindRevisions, dirEntry.file.Id, dirEntry.exportMimeType),
+		Gen:  uint64(dirEntry.file.Version),
+	}
+	if node = inodes.Reuse(&d.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	cfg := revisions.Config{
+		Logger:         d.logger,
+		Config:         d.config,
+		User:           d.user,
+		Drive:          d.drive,
+		Trashed:        d.trashed,
+		File:           dirEntry.file,
+		ExportMimeType: dirEntry.exportMimeType,
+	}
+	node = d.NewInode(ctx, revisions.New(&cfg), attr)
+	return node, fs.OK
+}
+
+func (d *Directory) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := d.logger.With("action", "Lookup", "name", name)
+
+	// Revisions are hidden from listings and only reachable by name
+	if filename, found := strings.CutSuffix(name, revisions.Suffix); found && filename != "" {
+		node, errno = d.lookupRevisions(ctx, logger, name, filename, out)
+		if errno != syscall.ENOENT {
+			return node, errno
 		}
+	}
 
-		file = fl.Files[0]
-		logger.Debug("Storing in cache")
-		d.lookupCache.Store(name, file, d.config.Cache.Expiration)
+	dirEntry, errno := d.resolve(ctx, logger, name)
+	if errno != fs.OK {
+		return nil, errno
+	}
+
+	if dirEntry.mode() == syscall.S_IFDIR {
+		d.config.Timeouts.Folders.EntryOut(out)
 	} else {
-		logger.Debug("Using cache")
+		d.config.Timeouts.Files.EntryOut(out)
 	}
+	d.invalidateVersion(logger, dirEntry)
 
+	attr := dirEntry.stableAttr()
+	if node = inodes.Reuse(&d.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	file := dirEntry.file
 	switch file.MimeType {
-	case "application/vnd.google-apps.folder":
+	case FolderMimeType:
 		cfg := Config{
 			Logger:    d.logger,
 			Config:    d.config,
@@ -190,77 +359,89 @@ func (d *Directory) Lookup(ctx context.Context, name string, out *fuse.EntryOut)
 			Drive:     d.drive,
 			Trashed:   d.trashed,
 			Directory: file,
+			SharedBy:  d.sharedBy,
 		}
-		node = d.NewInode(ctx, New(&cfg), fs.StableAttr{Mode: syscall.S_IFDIR})
+		node = d.NewInode(ctx, New(&cfg), attr)
+	case ShortcutMimeType:
+		node = d.NewInode(ctx, newShortcut(d, file), attr)
 	default:
 		cfg := files.Config{
-			Logger:  d.logger,
-			Config:  d.config,
-			User:    d.user,
-			Drive:   d.drive,
-			Trashed: d.trashed,
-			File:    file,
+			Logger:         d.logger,
+			Config:         d.config,
+			User:           d.user,
+			Drive:          d.drive,
+			Trashed:        d.trashed,
+			File:           file,
+			ExportMimeType: dirEntry.exportMimeType,
 		}
-		node = d.NewInode(ctx, files.New(&cfg), fs.StableAttr{Mode: syscall.S_IFREG})
+		node = d.NewInode(ctx, files.New(&cfg), attr)
 	}
 	return node, fs.OK
 }
 
-func (d *Directory) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
-	logger := d.logger.With("action", "Readdir")
+// Lists the directory contents filling the lookup cache
+func (d *Directory) listEntries(ctx context.Context, svc *drive.Service, logger *slog.Logger) (dirEntries []fuse.DirEntry, err error) {
+	call, err := d.ListCall(svc, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare list call: %w", err)
+	}
 
-	logger.Debug("Checking cache")
-	dirEntries, found := d.readdirCache.Load(ReaddirCacheKey)
-	if !found {
+	logger.Debug("Pulling file list")
+	var files []*drive.File
+	err = call.
+		Context(ctx).
+		PageSize(1_000).
+		Pages(ctx, func(fl *drive.FileList) (err error) {
+			logger.Debug("Retrieving page", "page-length", len(fl.Files))
+			for _, file := range fl.Files {
+				logger.Debug("Found file or directory", "name", file.Name)
+				files = append(files, file)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve files: %w", err)
+	}
+
+	for _, e := range d.groupEntries(files) {
+		d.lookupCache.Store(e.name, e, d.config.Cache.Expiration)
+		d.register(e.file)
+		d.invalidateVersion(logger, e)
+		if e.alias {
+			continue
+		}
+		dirEntries = append(dirEntries, fuse.DirEntry{
+			Mode: e.mode(),
+			Name: e.name,
+			Ino:  e.stableAttr().Ino,
+		})
+	}
+
+	return dirEntries, nil
+}
+
+// Returns the cached directory contents, listing them on a miss
+func (d *Directory) loadEntries(ctx context.Context, logger *slog.Logger) (dirEntries []fuse.DirEntry, err error) {
+	return d.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, d.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
 		client := d.HttpClient(ctx)
 
 		logger.Debug("Preparing drive service")
 		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
-		}
-
-		call, err := d.ListCall(driveSvc, "")
-		if err != nil {
-			logger.Error("Failed to prepare list call", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
+		return d.listEntries(ctx, driveSvc, logger)
+	})
+}
 
-		logger.Debug("Pulling file list")
-		err = call.
-			Context(ctx).
-			PageSize(1_000).
-			Pages(ctx, func(fl *drive.FileList) (err error) {
-				logger.Debug("Retrieving page", "page-length", len(fl.Files))
-				for _, file := range fl.Files {
-					logger.Debug("Found file or directory", "name", file.Name)
-					d.lookupCache.Store(file.Name, file, d.config.Cache.Expiration)
-
-					switch file.MimeType {
-					case "application/vnd.google-apps.folder":
-						dirEntries = append(dirEntries, fuse.DirEntry{
-							Mode: syscall.S_IFDIR,
-							Name: file.Name,
-						})
-					default:
-						dirEntries = append(dirEntries, fuse.DirEntry{
-							Mode: syscall.S_IFREG,
-							Name: file.Name,
-						})
-					}
-				}
-				return nil
-			})
-		if err != nil {
-			logger.Error("failed to retrieve files", "error-msg", err)
-			return nil, fs.ToErrno(err)
-		}
+func (d *Directory) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := d.logger.With("action", "Readdir")
 
-		logger.Debug("Storing in cache")
-		d.readdirCache.Store(ReaddirCacheKey, dirEntries, d.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+	logger.Debug("Loading directory")
+	dirEntries, err := d.loadEntries(ctx, logger)
+	if err != nil {
+		logger.Error("failed to list directory", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
 	ds = fs.NewListDirStream(dirEntries)
@@ -288,10 +469,19 @@ func (d *Directory) fileInfo() (modTime, creationTime time.Time, err error) {
 func (d *Directory) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) (errno syscall.Errno) {
 	modTime, creationTime, err := d.fileInfo()
 	if err != nil {
-		return fs.ToErrno(err)
+		return httputils.ToErrno(err)
 	}
 
 	out.Ctime = uint64(creationTime.Unix())
 	out.Mtime = uint64(modTime.Unix())
+	d.config.Timeouts.Folders.AttrOut(out)
 	return fs.OK
 }
+
+func (d *Directory) Getxattr(ctx context.Context, attr string, dest []byte) (size uint32, errno syscall.Errno) {
+	return xattrs.Getxattr(d.directory, attr, dest)
+}
+
+func (d *Directory) Listxattr(ctx context.Context, dest []byte) (size uint32, errno syscall.Errno) {
+	return xattrs.Listxattr(d.directory, dest)
+}
@@ -0,0 +1,196 @@
+package directory
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/exports"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"google.golang.org/api/drive/v3"
+)
+
+// Number of trailing characters of the file ID used to disambiguate names
+const IdSuffixLength = 7
+
+// Remote object referenced by a directory entry name
+type entry struct {
+	name string
+	file *drive.File
+	// Export format selected by the entry name, empty for the preferred one
+	exportMimeType string
+	// Aliases resolve on Lookup but are not listed by Readdir
+	alias bool
+}
+
+// Form of the entry kept by the metadata store
+type persistedEntry struct {
+	Name           string      `json:"name"`
+	File           *drive.File `json:"file"`
+	ExportMimeType string      `json:"export-mime-type,omitempty"`
+	Alias          bool        `json:"alias,omitempty"`
+}
+
+func (e *entry) MarshalJSON() (contents []byte, err error) {
+	return json.Marshal(persistedEntry{
+		Name:           e.name,
+		File:           e.file,
+		ExportMimeType: e.exportMimeType,
+		Alias:          e.alias,
+	})
+}
+
+func (e *entry) UnmarshalJSON(contents []byte) (err error) {
+	var persisted persistedEntry
+	err = json.Unmarshal(contents, &persisted)
+	if err != nil {
+		return err
+	}
+	if persisted.File == nil {
+		return errors.New("entry without file")
+	}
+	e.name = persisted.Name
+	e.file = persisted.File
+	e.exportMimeType = persisted.ExportMimeType
+	e.alias = persisted.Alias
+	return nil
+}
+
+func (e *entry) mode() (mode uint32) {
+	switch e.file.MimeType {
+	case FolderMimeType:
+		return syscall.S_IFDIR
+	case ShortcutMimeType:
+		return syscall.S_IFLNK
+	default:
+		return syscall.S_IFREG
+	}
+}
+
+// Stable attributes of the entry. Every export format is a different inode and
+// a new version of the file generates a new one
+func (e *entry) stableAttr() (attr fs.StableAttr) {
+	var ino uint64
+	if e.exportMimeType == "" {
+		ino = inodes.Ino(inodes.KindDriveFile, e.file.Id)
+	} else {
+		ino = inodes.Ino(inodes.KindDriveFile, e.file.Id, e.exportMimeType)
+	}
+	return fs.StableAttr{Mode: e.mode(), Ino: ino, Gen: uint64(e.file.Version)}
+}
+
+var disambiguatedRegexp = regexp.MustCompile(`^(.*) \([A-Za-z0-9_-]+\)(\.[^.]*)?$`)
+
+// Inserts the ID suffix before the extension: report.pdf -> report (1AbCdEf).pdf
+func disambiguate(name string, id string) (disambiguated string) {
+	suffix := id
+	if len(suffix) > IdSuffixLength {
+		suffix = suffix[len(suffix)-IdSuffixLength:]
+	}
+
+	ext := path.Ext(name)
+	if ext == name {
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + " (" + suffix + ")" + ext
+}
+
+// Remote names that may be listed under the entry name, ordered from the most
+// to the least specific
+func remoteCandidates(name string) (candidates []string) {
+	name = names.Decode(name)
+
+	add := func(candidate string) {
+		if candidate != "" && !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	bases := []string{name}
+	if match := disambiguatedRegexp.FindStringSubmatch(name); match != nil {
+		bases = append(bases, match[1]+match[2])
+	}
+
+	for _, base := range bases {
+		add(base)
+		if ext := path.Ext(base); ext != "" && ext != base {
+			add(strings.TrimSuffix(base, ext))
+		}
+	}
+	return candidates
+}
+
+// Computes the entries of the files. Collisions are checked on the final
+// names, after the export extensions are added, so a Sheet "Budget" and a
+// binary "Budget.xlsx" don't share "Budget.xlsx". The oldest file producing a
+// name keeps it and the rest are disambiguated with their ID suffix, so names
+// don't depend on the listing order. Listed names are claimed before aliases.
+// Every file able to produce a name must be passed for it to be correct, which
+// remoteCandidates guarantees
+func (d *Directory) groupEntries(files []*drive.File) (entries []*entry) {
+	sorted := slices.Clone(files)
+	slices.SortFunc(sorted, func(a, b *drive.File) int {
+		if c := strings.Compare(a.CreatedTime, b.CreatedTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	claimed := map[string]*drive.File{}
+	claim := func(file *drive.File, name string) (entryName string, ok bool) {
+		entryName = names.Encode(name)
+		if owner, found := claimed[entryName]; found {
+			if owner == file {
+				return "", false
+			}
+			entryName = names.Encode(disambiguate(name, file.Id))
+			if _, found := claimed[entryName]; found {
+				return "", false
+			}
+		}
+		claimed[entryName] = file
+		return entryName, true
+	}
+
+	for _, file := range sorted {
+		if !exports.IsNative(file) {
+			if entryName, ok := claim(file, file.Name); ok {
+				entries = append(entries, &entry{name: entryName, file: file})
+			}
+			continue
+		}
+
+		for _, mimeType := range d.exportFormats(file) {
+			if entryName, ok := claim(file, exports.Filename(file.Name, mimeType)); ok {
+				entries = append(entries, &entry{name: entryName, file: file, exportMimeType: mimeType})
+			}
+		}
+	}
+
+	// Undecorated names resolve to the preferred format
+	for _, file := range sorted {
+		if !exports.IsNative(file) {
+			continue
+		}
+		if entryName, ok := claim(file, file.Name); ok {
+			preferred := exports.Preferred(&d.config.Export, file)
+			entries = append(entries, &entry{name: entryName, file: file, exportMimeType: preferred, alias: true})
+		}
+	}
+	return entries
+}
+
+// Export formats listed for a Google-native document
+func (d *Directory) exportFormats(file *drive.File) (formats []string) {
+	formats = exports.Formats(&d.config.Export, file)
+	if d.config.Export.AllFormats || len(formats) == 0 {
+		return formats
+	}
+	return formats[:1]
+}
@@ -0,0 +1,108 @@
+package directory
+
+import (
+	"testing"
+
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	spreadsheetMimeType = "application/vnd.google-apps.spreadsheet"
+	xlsxMimeType        = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+func spreadsheet(id, name, createdTime string) (file *drive.File) {
+	return &drive.File{
+		Id:          id,
+		Name:        name,
+		MimeType:    spreadsheetMimeType,
+		CreatedTime: createdTime,
+		ExportLinks: map[string]string{xlsxMimeType: "https://export/" + id},
+	}
+}
+
+func binary(id, name, createdTime string) (file *drive.File) {
+	return &drive.File{
+		Id:          id,
+		Name:        name,
+		MimeType:    "application/octet-stream",
+		CreatedTime: createdTime,
+	}
+}
+
+func TestDirectory_groupEntries(t *testing.T) {
+	type Test struct {
+		Name  string
+		Files []*drive.File
+		// Entry name and ID of the file it resolves to
+		Listed  map[string]string
+		Aliases map[string]string
+	}
+	tests := []Test{
+		{
+			Name: "Same name",
+			Files: []*drive.File{
+				binary("newer000000", "report.pdf", "2024-02-01T00:00:00Z"),
+				binary("older000000", "report.pdf", "2024-01-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				"report.pdf":           "older000000",
+				"report (r000000).pdf": "newer000000",
+			},
+		},
+		{
+			Name: "Export colliding with binary",
+			Files: []*drive.File{
+				binary("binary00000", "Budget.xlsx", "2024-02-01T00:00:00Z"),
+				spreadsheet("sheet000000", "Budget", "2024-01-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				"Budget.xlsx":           "sheet000000",
+				"Budget (ry00000).xlsx": "binary00000",
+			},
+			Aliases: map[string]string{
+				"Budget": "sheet000000",
+			},
+		},
+		{
+			Name: "Binary colliding with alias",
+			Files: []*drive.File{
+				binary("binary00000", "Budget", "2024-02-01T00:00:00Z"),
+				spreadsheet("sheet000000", "Budget", "2024-01-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				"Budget":      "binary00000",
+				"Budget.xlsx": "sheet000000",
+			},
+			Aliases: map[string]string{
+				"Budget (t000000)": "sheet000000",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			d := &Directory{config: &config.Config{}}
+			listed := map[string]string{}
+			aliases := map[string]string{}
+			for _, e := range d.groupEntries(test.Files) {
+				target := listed
+				if e.alias {
+					target = aliases
+				}
+				_, found := target[e.name]
+				assertions.False(found, "duplicated name %s", e.name)
+				target[e.name] = e.file.Id
+			}
+
+			assertions.Equal(test.Listed, listed, "listed")
+			if test.Aliases == nil {
+				test.Aliases = map[string]string{}
+			}
+			assertions.Equal(test.Aliases, aliases, "aliases")
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/changes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/exports"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const ShortcutNodeName = "shortcut-node"
+
+const ShortcutMimeType = "application/vnd.google-apps.shortcut"
+
+// Directory at the root of the mount exposing every file by its ID
+const ByIdDirName = ".by-id"
+
+// Maximum number of folders walked while resolving a shortcut target
+const MaxShortcutDepth = 64
+
+const TargetCacheKey = 0
+
+// Shortcut exposes a Drive shortcut as a symlink to its target. The path of
+// the target is cached until it expires or the target or any of its folders
+// change
+type Shortcut struct {
+	fs.Inode
+
+	targetCache cache.Cache[int, string]
+	file        *drive.File
+	parent      *Directory
+	logger      *slog.Logger
+}
+
+func newShortcut(parent *Directory, file *drive.File) (s *Shortcut) {
+	return &Shortcut{
+		file:   file,
+		parent: parent,
+		logger: parent.logger.With("inode", ShortcutNodeName, "filename", file.Name),
+	}
+}
+
+var (
+	_ fs.NodeReadlinker   = (*Shortcut)(nil)
+	_ changes.Invalidator = (*Shortcut)(nil)
+)
+
+// Invalidate drops the target path when the target, one of its folders or
+// their contents change, since any of them may rename it
+func (s *Shortcut) Invalidate(change *drive.Change) {
+	logger := s.logger.With("action", "Invalidate", "file-id", change.FileId)
+
+	logger.Debug("Invalidating target")
+	s.targetCache.Delete(TargetCacheKey)
+}
+
+// Number of directories between the inode and the root of the drive tree.
+// ok is false when the inode is not inside a drive tree
+func driveDepth(inode *fs.Inode) (depth int, ok bool) {
+	for current := inode; current != nil; depth++ {
+		if dir, isDir := current.Operations().(*Directory); isDir && dir.directory == nil {
+			return depth, true
+		}
+		_, current = current.Parent()
+	}
+	return depth, false
+}
+
+// Path relative to the shortcut pointing to the target in the .by-id directory
+func (s *Shortcut) byIdTarget() (target string) {
+	_, parent := s.Parent()
+	depth := len(strings.Split(parent.Path(nil), "/"))
+	if parent.IsRoot() {
+		depth = 0
+	}
+	return path.Join(strings.Repeat("../", depth), ByIdDirName, s.file.ShortcutDetails.TargetId)
+}
+
+// Listed name of the file inside the parent folder, nil for the drive root
+func (s *Shortcut) entryName(ctx context.Context, svc *drive.Service, parent *drive.File, file *drive.File) (name string, err error) {
+	dir := &Directory{
+		config:    s.parent.config,
+		user:      s.parent.user,
+		drive:     s.parent.drive,
+		trashed:   s.parent.trashed,
+		directory: parent,
+	}
+	// Siblings able to claim the listed name of the file
+	listedName := file.Name
+	if exports.IsNative(file) {
+		if formats := dir.exportFormats(file); len(formats) > 0 {
+			listedName = exports.Filename(file.Name, formats[0])
+		}
+	}
+	var siblings []*drive.File
+	for _, candidate := range remoteCandidates(names.Encode(listedName)) {
+		group, err := dir.findFiles(ctx, svc, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to list siblings: %w", err)
+		}
+		siblings = append(siblings, group...)
+	}
+	for _, e := range dir.groupEntries(siblings) {
+		if e.file.Id == file.Id && !e.alias {
+			return e.name, nil
+		}
+	}
+	return names.Encode(file.Name), nil
+}
+
+// Path of the target relative to the root of the drive tree, empty when it
+// lives outside of it
+func (s *Shortcut) drivePath(ctx context.Context, svc *drive.Service) (target string, err error) {
+	const fields = "id,name,parents,mimeType,createdTime,exportLinks,driveId,trashed"
+
+	var rootId string
+	switch {
+	case s.parent.sharedBy != "":
+		return "", nil
+	case s.parent.drive != nil:
+		rootId = s.parent.drive.Id
+	default:
+		root, err := svc.Files.Get("root").Fields("id").Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve root folder: %w", err)
+		}
+		rootId = root.Id
+	}
+
+	file, err := svc.Files.
+		Get(s.file.ShortcutDetails.TargetId).
+		Fields(fields).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve target: %w", err)
+	}
+	if file.Trashed != s.parent.trashed {
+		return "", nil
+	}
+
+	var ancestry []*drive.File
+	for range MaxShortcutDepth {
+		changes.Register(s.parent.config.Changes, file.Id, s)
+		ancestry = append(ancestry, file)
+		if len(file.Parents) == 0 {
+			return "", nil
+		}
+		if file.Parents[0] == rootId {
+			break
+		}
+		file, err = svc.Files.
+			Get(file.Parents[0]).
+			Fields(fields).
+			SupportsAllDrives(true).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve target parent: %w", err)
+		}
+	}
+	if ancestry[len(ancestry)-1].Parents[0] != rootId {
+		return "", nil
+	}
+
+	slices.Reverse(ancestry)
+	components := make([]string, 0, len(ancestry))
+	var parent *drive.File
+	for _, file := range ancestry {
+		name, err := s.entryName(ctx, svc, parent, file)
+		if err != nil {
+			return "", err
+		}
+		components = append(components, name)
+		parent = file
+	}
+	return path.Join(components...), nil
+}
+
+func (s *Shortcut) Readlink(ctx context.Context) (target []byte, errno syscall.Errno) {
+	logger := s.logger.With("action", "Readlink")
+
+	if s.file.ShortcutDetails == nil {
+		logger.Error("Missing shortcut details")
+		return nil, syscall.EIO
+	}
+
+	_, parent := s.Parent()
+	depth, ok := driveDepth(parent)
+	if !ok {
+		logger.Debug("Not inside a drive tree")
+		return []byte(s.byIdTarget()), fs.OK
+	}
+
+	logger.Debug("Loading target")
+	drivePath, err := s.targetCache.LoadOrFetch(ctx, TargetCacheKey, s.parent.config.Cache.Expiration, func(ctx context.Context) (drivePath string, err error) {
+		client := s.parent.HttpClient(ctx)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return "", fmt.Errorf("failed to prepare drive service: %w", err)
+		}
+		return s.drivePath(ctx, driveSvc)
+	})
+	if err != nil {
+		logger.Debug("Failed to resolve target in drive", "error-msg", err)
+	}
+	if drivePath == "" {
+		logger.Debug("Target outside of the drive")
+		return []byte(s.byIdTarget()), fs.OK
+	}
+
+	return []byte(path.Join(strings.Repeat("../", depth), drivePath)), fs.OK
+}
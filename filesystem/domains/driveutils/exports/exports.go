@@ -0,0 +1,99 @@
+package exports
+
+import (
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"google.golang.org/api/drive/v3"
+)
+
+// Prefix of the MIME types of Google-native documents
+const NativePrefix = "application/vnd.google-apps."
+
+var extensions = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.oasis.opendocument.text":                                   ".odt",
+	"application/vnd.oasis.opendocument.spreadsheet":                            ".ods",
+	"application/x-vnd.oasis.opendocument.spreadsheet":                          ".ods",
+	"application/vnd.oasis.opendocument.presentation":                           ".odp",
+	"application/vnd.google-apps.script+json":                                   ".json",
+	"application/pdf":           ".pdf",
+	"application/rtf":           ".rtf",
+	"application/epub+zip":      ".epub",
+	"application/zip":           ".zip",
+	"text/plain":                ".txt",
+	"text/html":                 ".html",
+	"text/csv":                  ".csv",
+	"text/tab-separated-values": ".tsv",
+	"text/markdown":             ".md",
+	"image/jpeg":                ".jpg",
+	"image/png":                 ".png",
+	"image/svg+xml":             ".svg",
+}
+
+// Extension returns the file extension, including the leading dot, used for
+// an export MIME type
+func Extension(mimeType string) (ext string) {
+	if ext, found := extensions[mimeType]; found {
+		return ext
+	}
+	exts, _ := mime.ExtensionsByType(mimeType)
+	if len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// Filename returns the name decorated with the extension of the export format.
+// Names already ending with the extension are returned unmodified
+func Filename(name, mimeType string) (filename string) {
+	ext := Extension(mimeType)
+	if strings.HasSuffix(name, ext) {
+		return name
+	}
+	return name + ext
+}
+
+// IsNative reports if the file is a Google-native document that can be exported
+func IsNative(file *drive.File) (native bool) {
+	return strings.HasPrefix(file.MimeType, NativePrefix) && len(file.ExportLinks) > 0
+}
+
+// Formats returns every export MIME type available for the file sorted by
+// preference. Formats not listed in the policy are sorted alphabetically
+func Formats(c *config.Export, file *drive.File) (formats []string) {
+	preferred, found := c.Formats[file.MimeType]
+	if !found {
+		preferred = config.DefaultExportFormats[file.MimeType]
+	}
+
+	formats = make([]string, 0, len(file.ExportLinks))
+	for _, mimeType := range preferred {
+		if _, found := file.ExportLinks[mimeType]; found && !slices.Contains(formats, mimeType) {
+			formats = append(formats, mimeType)
+		}
+	}
+
+	rest := make([]string, 0, len(file.ExportLinks))
+	for mimeType := range file.ExportLinks {
+		if !slices.Contains(formats, mimeType) {
+			rest = append(rest, mimeType)
+		}
+	}
+	slices.Sort(rest)
+	return append(formats, rest...)
+}
+
+// Preferred returns the export MIME type to use for the file, empty when it
+// can't be exported
+func Preferred(c *config.Export, file *drive.File) (mimeType string) {
+	formats := Formats(c, file)
+	if len(formats) == 0 {
+		return ""
+	}
+	return formats[0]
+}
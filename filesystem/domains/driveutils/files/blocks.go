@@ -0,0 +1,341 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Returns the contents of the remote file between start and end, both
+// included
+type RangeFetcher func(ctx context.Context, start, end int64) (body io.ReadCloser, err error)
+
+// ErrBlocksRemoved is returned when the blocks file is removed, usually
+// evicted, while blocks are downloaded
+var ErrBlocksRemoved = errors.New("blocks file removed")
+
+// BlockCache tracks the blocks of a remote file already stored in a sparse
+// local file. The bitmap of present blocks is persisted next to it so the
+// blocks survive remounts
+type BlockCache struct {
+	mu          sync.Mutex
+	filename    string
+	mapFilename string
+	size        int64
+	blockSize   int64
+	present     []byte
+	// Blocks being downloaded, closed once done
+	pending map[int64]chan struct{}
+	// Set once the contents mismatched MaxDownloadAttempts times
+	failed error
+
+	// Held while the complete contents are verified
+	verifyMu       sync.Mutex
+	md5Checksum    string
+	sha256Checksum string
+	verified       bool
+	attempts       int
+}
+
+// Suffixes of the files used by a block cache
+const (
+	BlocksSuffix    = ".blocks"
+	BlockMapSuffix  = ".blocks.map"
+	blockFilePerm   = 0o600
+	bitsPerMapEntry = 8
+)
+
+// NewBlockCache opens the block cache of the file. The contents are verified
+// against the checksums once every block is present
+func NewBlockCache(filename string, size, blockSize int64, md5Checksum, sha256Checksum string) (b *BlockCache, err error) {
+	b = &BlockCache{
+		filename:       filename + BlocksSuffix,
+		mapFilename:    filename + BlockMapSuffix,
+		size:           size,
+		blockSize:      blockSize,
+		pending:        make(map[int64]chan struct{}),
+		md5Checksum:    md5Checksum,
+		sha256Checksum: sha256Checksum,
+	}
+
+	mapLength := (b.blocks() + bitsPerMapEntry - 1) / bitsPerMapEntry
+	b.present, err = os.ReadFile(b.mapFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read block map: %w", err)
+	}
+	if int64(len(b.present)) != mapLength {
+		// Missing or belonging to a different file size
+		b.present = make([]byte, mapLength)
+	}
+
+	file, err := os.OpenFile(b.filename, os.O_RDWR|os.O_CREATE, blockFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blocks file: %w", err)
+	}
+	defer file.Close()
+
+	err = file.Truncate(size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate blocks file: %w", err)
+	}
+	return b, nil
+}
+
+// Number of blocks of the file
+func (b *BlockCache) blocks() (count int64) {
+	return (b.size + b.blockSize - 1) / b.blockSize
+}
+
+func (b *BlockCache) has(block int64) (found bool) {
+	return b.present[block/bitsPerMapEntry]&(1<<(block%bitsPerMapEntry)) != 0
+}
+
+// Reports if every block is present. Must be called holding the lock
+func (b *BlockCache) complete() (ok bool) {
+	for block := range b.blocks() {
+		if !b.has(block) {
+			return false
+		}
+	}
+	return true
+}
+
+// Drops every block, they are downloaded again when read. Must be called
+// holding the lock
+func (b *BlockCache) drop() (err error) {
+	b.present = make([]byte, len(b.present))
+	err = os.Remove(b.mapFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove block map: %w", err)
+	}
+	return nil
+}
+
+// Reports if the blocks file is still in place
+func (b *BlockCache) exists() (err error) {
+	_, err = os.Stat(b.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlocksRemoved
+	}
+	return err
+}
+
+// Marks the blocks as present. A map is never written for a removed blocks
+// file, it would describe the zeros of a new one as contents
+func (b *BlockCache) mark(first, last int64) (err error) {
+	err = b.exists()
+	if err != nil {
+		return err
+	}
+
+	present := append([]byte(nil), b.present...)
+	for block := first; block <= last; block++ {
+		present[block/bitsPerMapEntry] |= 1 << (block % bitsPerMapEntry)
+	}
+	err = os.WriteFile(b.mapFilename, present, blockFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to write block map: %w", err)
+	}
+
+	// Removed while writing the map
+	err = b.exists()
+	if err != nil {
+		os.Remove(b.mapFilename)
+		return err
+	}
+	b.present = present
+	return nil
+}
+
+// Downloads the blocks from first to last, both included
+func (b *BlockCache) download(ctx context.Context, fetch RangeFetcher, first, last int64) (err error) {
+	start := first * b.blockSize
+	end := min((last+1)*b.blockSize, b.size) - 1
+
+	body, err := fetch(ctx, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to fetch range: %w", err)
+	}
+	defer body.Close()
+
+	file, err := os.OpenFile(b.filename, os.O_WRONLY, blockFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open blocks file: %w", err)
+	}
+	defer file.Close()
+
+	written, err := io.Copy(io.NewOffsetWriter(file, start), io.LimitReader(body, end-start+1))
+	if err != nil {
+		return fmt.Errorf("failed to write blocks: %w", err)
+	}
+	if written != end-start+1 {
+		return fmt.Errorf("short range: expecting %d bytes, received %d", end-start+1, written)
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync blocks: %w", err)
+	}
+	return nil
+}
+
+// Ensure downloads every missing block between first and last, both included,
+// and verifies the contents once complete. Reports if blocks were written, and
+// fails with ErrChecksumMismatch when the contents mismatched
+// MaxDownloadAttempts times
+func (b *BlockCache) Ensure(ctx context.Context, fetch RangeFetcher, first, last int64) (filled bool, err error) {
+	b.mu.Lock()
+	err = b.failed
+	b.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	filled, err = b.ensure(ctx, fetch, first, last)
+	if err != nil {
+		return filled, err
+	}
+	refilled, err := b.verify(ctx, fetch)
+	return filled || refilled, err
+}
+
+// Downloads the missing blocks grouping contiguous blocks into a single
+// request. The lock is only held to claim the blocks, blocks claimed by other
+// readers are waited for
+func (b *BlockCache) ensure(ctx context.Context, fetch RangeFetcher, first, last int64) (filled bool, err error) {
+	last = min(last, b.blocks()-1)
+	for {
+		b.mu.Lock()
+		runStart, runEnd, wait := b.claim(first, last)
+		b.mu.Unlock()
+
+		switch {
+		case wait != nil:
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				return filled, ctx.Err()
+			}
+		case runStart > runEnd:
+			return filled, nil
+		default:
+			err = b.download(ctx, fetch, runStart, runEnd)
+
+			b.mu.Lock()
+			if err == nil {
+				err = b.mark(runStart, runEnd)
+			}
+			for block := runStart; block <= runEnd; block++ {
+				close(b.pending[block])
+				delete(b.pending, block)
+			}
+			b.mu.Unlock()
+
+			if err != nil {
+				return filled, err
+			}
+			filled = true
+		}
+	}
+}
+
+// Verifies the contents against the remote checksums once every block is
+// present. Mismatching contents are dropped and downloaded again until
+// MaxDownloadAttempts, reports if blocks were downloaded again
+func (b *BlockCache) verify(ctx context.Context, fetch RangeFetcher) (filled bool, err error) {
+	b.verifyMu.Lock()
+	defer b.verifyMu.Unlock()
+
+	for !b.verified {
+		b.mu.Lock()
+		complete, failed := b.complete(), b.failed
+		b.mu.Unlock()
+		if failed != nil {
+			return filled, failed
+		}
+		if !complete {
+			return filled, nil
+		}
+
+		status, err := b.hash()
+		if err != nil && !errors.Is(err, ErrChecksumMismatch) {
+			return filled, err
+		}
+		Verifications.Add(status, 1)
+		if err == nil {
+			b.verified = true
+			return filled, nil
+		}
+		b.attempts++
+
+		b.mu.Lock()
+		if b.attempts >= MaxDownloadAttempts {
+			b.failed = err
+		}
+		dropErr := b.drop()
+		b.mu.Unlock()
+		if dropErr != nil {
+			return filled, dropErr
+		}
+		if b.attempts >= MaxDownloadAttempts {
+			return filled, err
+		}
+
+		_, err = b.ensure(ctx, fetch, 0, b.blocks()-1)
+		if err != nil {
+			return filled, err
+		}
+		filled = true
+	}
+	return filled, nil
+}
+
+// Hashes the blocks file comparing it against the checksums
+func (b *BlockCache) hash() (status string, err error) {
+	file, err := os.Open(b.filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Unverified, ErrBlocksRemoved
+		}
+		return Unverified, fmt.Errorf("failed to open blocks file: %w", err)
+	}
+	defer file.Close()
+
+	verifier := newVerifier(b.md5Checksum, b.sha256Checksum)
+	_, err = io.Copy(verifier, file)
+	if err != nil {
+		return Unverified, fmt.Errorf("failed to hash blocks: %w", err)
+	}
+	return verifier.Verify()
+}
+
+// Claims the first run of missing blocks not being downloaded. Returns the
+// channel of the first block downloaded by another reader when there is
+// nothing to claim, and an empty run when every block is present. Must be
+// called holding the lock
+func (b *BlockCache) claim(first, last int64) (runStart, runEnd int64, wait chan struct{}) {
+	for block := first; block <= last; block++ {
+		if b.has(block) {
+			continue
+		}
+		if pending, found := b.pending[block]; found {
+			if wait == nil {
+				wait = pending
+			}
+			continue
+		}
+
+		runEnd = block
+		for runEnd+1 <= last && !b.has(runEnd+1) && b.pending[runEnd+1] == nil {
+			runEnd++
+		}
+		for claimed := block; claimed <= runEnd; claimed++ {
+			b.pending[claimed] = make(chan struct{})
+		}
+		return block, runEnd, nil
+	}
+	return 1, 0, wait
+}
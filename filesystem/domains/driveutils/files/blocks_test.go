@@ -0,0 +1,105 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Fetcher serving the ranges of contents, counting the requests
+func rangeFetcher(contents []byte, requests *atomic.Int64) (fetch RangeFetcher) {
+	return func(ctx context.Context, start, end int64) (body io.ReadCloser, err error) {
+		requests.Add(1)
+		return io.NopCloser(bytes.NewReader(contents[start : end+1])), nil
+	}
+}
+
+func TestBlockCache_Ensure(t *testing.T) {
+	assertions := assert.New(t)
+
+	contents := []byte("0123456789abcdef")
+	var requests atomic.Int64
+	fetch := rangeFetcher(contents, &requests)
+
+	filename := filepath.Join(t.TempDir(), "1AbCdEf")
+	blocks, err := NewBlockCache(filename, int64(len(contents)), 4, "", "")
+	if !assertions.Nil(err, "failed to create block cache") {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := blocks.Ensure(context.Background(), fetch, 0, 3)
+			assertions.Nil(err, "failed to ensure blocks")
+		}()
+	}
+	wg.Wait()
+
+	stored, err := os.ReadFile(filename + BlocksSuffix)
+	if !assertions.Nil(err, "failed to read blocks") {
+		return
+	}
+	assertions.Equal(contents, stored, "contents")
+	assertions.LessOrEqual(requests.Load(), int64(4), "blocks downloaded once")
+
+	// The map survives reopening
+	reopened, err := NewBlockCache(filename, int64(len(contents)), 4, "", "")
+	if !assertions.Nil(err, "failed to reopen block cache") {
+		return
+	}
+	requests.Store(0)
+	filled, err := reopened.Ensure(context.Background(), fetch, 0, 3)
+	assertions.Nil(err, "failed to ensure blocks")
+	assertions.False(filled, "filled")
+	assertions.Equal(int64(0), requests.Load(), "requests")
+}
+
+func TestBlockCache_EnsureRemoved(t *testing.T) {
+	assertions := assert.New(t)
+
+	contents := []byte("0123456789abcdef")
+	filename := filepath.Join(t.TempDir(), "1AbCdEf")
+	blocks, err := NewBlockCache(filename, int64(len(contents)), 4, "", "")
+	if !assertions.Nil(err, "failed to create block cache") {
+		return
+	}
+
+	// Evicted while the blocks are downloaded
+	var requests atomic.Int64
+	fetch := func(ctx context.Context, start, end int64) (body io.ReadCloser, err error) {
+		os.Remove(filename + BlocksSuffix)
+		return rangeFetcher(contents, &requests)(ctx, start, end)
+	}
+	_, err = blocks.Ensure(context.Background(), fetch, 0, 0)
+	assertions.NotNil(err, "blocks file removed")
+
+	_, err = os.Stat(filename + BlockMapSuffix)
+	assertions.ErrorIs(err, os.ErrNotExist, "map of a removed blocks file")
+
+	// A new block cache starts empty
+	blocks, err = NewBlockCache(filename, int64(len(contents)), 4, "", "")
+	if !assertions.Nil(err, "failed to recreate block cache") {
+		return
+	}
+	assertions.False(blocks.has(0), "block present")
+
+	// Removed after the download
+	err = os.Remove(filename + BlocksSuffix)
+	if !assertions.Nil(err, "failed to remove blocks") {
+		return
+	}
+	assertions.ErrorIs(blocks.mark(0, 0), ErrBlocksRemoved, "mark")
+	assertions.False(blocks.has(0), "block present")
+	_, err = os.Stat(filename + BlockMapSuffix)
+	assertions.ErrorIs(err, os.ErrNotExist, "map of a removed blocks file")
+}
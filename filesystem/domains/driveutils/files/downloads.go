@@ -0,0 +1,89 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Suffix of the temporary files receiving a download
+const PartialSuffix = ".part"
+
+// ErrReplaced is returned when the cache file kept being replaced by other
+// versions of the file before it could be opened
+var ErrReplaced = errors.New("cache file replaced by another version")
+
+// Held while a download replaces a cache file and its record
+var installMu sync.Mutex
+
+// In flight download shared by every caller of the same contents
+type download struct {
+	done    chan struct{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Downloads collapses concurrent downloads of the same contents into a
+// single one. The download keeps running while at least one caller is still
+// waiting for it
+type Downloads struct {
+	mu        sync.Mutex
+	downloads map[string]*download
+}
+
+// Coordinator shared by every file node
+var downloads = NewDownloads()
+
+func NewDownloads() (d *Downloads) {
+	return &Downloads{downloads: make(map[string]*download)}
+}
+
+// Do runs fn once for every key until it finishes. Callers arriving in the
+// meantime wait for the same result. A caller whose context is done stops
+// waiting, the download is only cancelled when no caller is left, and then
+// forgotten right away
+func (d *Downloads) Do(ctx context.Context, key string, fn func(ctx context.Context) error) (err error) {
+	d.mu.Lock()
+	dl, found := d.downloads[key]
+	if !found {
+		dlCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		dl = &download{done: make(chan struct{}), cancel: cancel}
+		d.downloads[key] = dl
+
+		go func() {
+			defer cancel()
+
+			err := fn(dlCtx)
+
+			d.mu.Lock()
+			if d.downloads[key] == dl {
+				delete(d.downloads, key)
+			}
+			d.mu.Unlock()
+
+			dl.err = err
+			close(dl.done)
+		}()
+	}
+	dl.waiters++
+	d.mu.Unlock()
+
+	select {
+	case <-dl.done:
+		return dl.err
+	case <-ctx.Done():
+		d.mu.Lock()
+		dl.waiters--
+		if dl.waiters == 0 {
+			// Callers arriving later start a new download instead of
+			// joining the cancelled one
+			dl.cancel()
+			if d.downloads[key] == dl {
+				delete(d.downloads, key)
+			}
+		}
+		d.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,96 @@
+package files
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+func TestDownloads_Do(t *testing.T) {
+	assertions := assert.New(t)
+
+	d := NewDownloads()
+	var calls atomic.Int64
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (err error) {
+		calls.Add(1)
+		<-release
+		return nil
+	}
+
+	done := make(chan error, 4)
+	for range 4 {
+		go func() { done <- d.Do(context.Background(), "key", fn) }()
+	}
+	assertions.Eventually(func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.downloads["key"] != nil && d.downloads["key"].waiters == 4
+	}, time.Second, time.Millisecond, "callers joined")
+
+	close(release)
+	for range 4 {
+		assertions.Nil(<-done, "failed to download")
+	}
+	assertions.Equal(int64(1), calls.Load(), "downloads")
+}
+
+func TestDownloads_DoCancelled(t *testing.T) {
+	assertions := assert.New(t)
+
+	d := NewDownloads()
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	cancelled := func(ctx context.Context) (err error) {
+		close(started)
+		// Keeps running after the cancellation, like a download still
+		// cleaning up
+		<-finish
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Do(ctx, "key", cancelled) }()
+	<-started
+	cancel()
+	assertions.ErrorIs(<-done, context.Canceled, "cancelled caller")
+
+	// Joining before the cancelled download returns starts a new one
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := d.Do(ctx, "key", func(ctx context.Context) (err error) {
+		return ctx.Err()
+	})
+	assertions.Nil(err, "caller after the cancellation")
+
+	close(finish)
+}
+
+func TestFile_downloadKey(t *testing.T) {
+	assertions := assert.New(t)
+
+	file := &drive.File{Id: "1AbCdEf", Version: 7, HeadRevisionId: "0B-head"}
+	newer := *file
+	newer.Version = 8
+	revision := &drive.Revision{Id: "0B-old"}
+
+	keys := map[string]string{
+		"head":     (&File{file: file}).downloadKey(),
+		"newer":    (&File{file: &newer}).downloadKey(),
+		"export":   (&File{file: file, exportMimeType: "application/pdf"}).downloadKey(),
+		"revision": (&File{file: file, revision: revision}).downloadKey(),
+	}
+	seen := map[string]string{}
+	for name, key := range keys {
+		if other, found := seen[key]; found {
+			assertions.Fail("shared download key", "%s and %s", name, other)
+		}
+		seen[key] = name
+	}
+	assertions.Equal(keys["head"], (&File{file: file}).downloadKey(), "stable")
+}
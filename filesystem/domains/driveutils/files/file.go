@@ -3,19 +3,26 @@ package files
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/exports"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/xattrs"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	"github.com/pluto-org-co/gsuitefs/internal/boundedmap"
 	"golang.org/x/sys/unix"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/drive/v3"
@@ -34,6 +41,13 @@ type File struct {
 	drive   *drive.Drive
 	logger  *slog.Logger
 	config  *config.Config
+	// Empty for files downloaded as is
+	exportMimeType string
+	// Set when the node exposes an old revision of the file
+	revision *drive.Revision
+
+	blocksMu sync.Mutex
+	blocks   *BlockCache
 }
 
 type Config struct {
@@ -43,52 +57,134 @@ type Config struct {
 	Drive   *drive.Drive
 	Trashed bool
 	File    *drive.File
+	// Export format for Google-native documents. When empty the preferred
+	// format of the export policy is used
+	ExportMimeType string
+	// Revision of the file to expose instead of the head contents
+	Revision *drive.Revision
 }
 
 func New(cfg *Config) (f *File) {
 	f = &File{
-		logger:  cfg.Logger.With("inode", NodeName, "filename", cfg.File.Name),
-		config:  cfg.Config,
-		user:    cfg.User,
-		drive:   cfg.Drive,
-		trashed: cfg.Trashed,
-		file:    cfg.File,
+		config:   cfg.Config,
+		user:     cfg.User,
+		drive:    cfg.Drive,
+		trashed:  cfg.Trashed,
+		file:     cfg.File,
+		revision: cfg.Revision,
+	}
+	if exports.IsNative(cfg.File) {
+		f.exportMimeType = cfg.ExportMimeType
+		if f.exportMimeType == "" {
+			f.exportMimeType = exports.Preferred(&cfg.Config.Export, cfg.File)
+		}
+	}
+	f.logger = cfg.Logger.With("inode", NodeName, "filename", f.Name(), "export-mime-type", f.exportMimeType)
+	if f.revision != nil {
+		f.logger = f.logger.With("revision-id", f.revision.Id)
 	}
 	return f
 }
 
+// Export format of the contents, empty for files downloaded as is
+func (f *File) ExportMimeType() (mimeType string) {
+	return f.exportMimeType
+}
+
+// Name of the file as listed in the filesystem
+func (f *File) Name() (name string) {
+	if f.exportMimeType == "" {
+		return f.file.Name
+	}
+	return exports.Filename(f.file.Name, f.exportMimeType)
+}
+
 var (
-	_ fs.NodeOpener    = (*File)(nil)
-	_ fs.NodeGetattrer = (*File)(nil)
+	_ fs.NodeOpener      = (*File)(nil)
+	_ fs.NodeReleaser    = (*File)(nil)
+	_ fs.NodeGetattrer   = (*File)(nil)
+	_ fs.NodeGetxattrer  = (*File)(nil)
+	_ fs.NodeListxattrer = (*File)(nil)
 )
 
-func (f *File) fileInfo() (cacheFilename string, modTime, creationTime time.Time, cached bool, err error) {
-	cacheFilename = path.Join(f.config.Cache.Path, f.file.Id)
+// Record describing the remote contents exposed by the node
+func (f *File) cacheRecord() (record *CacheRecord) {
+	record = &CacheRecord{
+		FileId:         f.file.Id,
+		Version:        f.file.Version,
+		HeadRevisionId: f.file.HeadRevisionId,
+		Md5Checksum:    f.file.Md5Checksum,
+		Sha256Checksum: f.file.Sha256Checksum,
+		ExportMimeType: f.exportMimeType,
+	}
+	if f.revision != nil {
+		// Revisions never change, the head of the file is irrelevant
+		record.Version = 0
+		record.HeadRevisionId = ""
+		record.Md5Checksum = f.revision.Md5Checksum
+		record.Sha256Checksum = ""
+		record.RevisionId = f.revision.Id
+	}
+	return record
+}
 
-	modTime, err = time.Parse(time.RFC3339, f.file.ModifiedTime)
-	if err != nil {
-		return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to parse file modtime: %w", err)
+// Local copy of the contents
+func (f *File) cacheFilename() (cacheFilename string) {
+	cacheFilename = path.Join(f.config.Cache.Path, f.file.Id)
+	if f.revision != nil {
+		cacheFilename += "@" + f.revision.Id
+	}
+	if f.exportMimeType != "" {
+		// Every export format is cached independently
+		cacheFilename += exports.Extension(f.exportMimeType)
 	}
+	return cacheFilename
+}
 
-	creationTime, err = time.Parse(time.RFC3339, f.file.CreatedTime)
-	if err != nil {
-		return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to parse file modtime: %w", err)
+// Base filename of the block cache used by streamed reads
+func (f *File) blocksFilename() (filename string) {
+	return path.Join(f.config.Cache.Path, f.file.Id+".v"+strconv.FormatInt(f.file.Version, 10))
+}
+
+// Cache file tracked by the cache manager while the node is open
+func (f *File) cacheKey() (key string) {
+	if f.streamed() {
+		return f.blocksFilename()
 	}
+	return f.cacheFilename()
+}
 
-	info, err := os.Stat(cacheFilename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cacheFilename, modTime, creationTime, false, nil
+func (f *File) fileInfo() (cacheFilename string, modTime, creationTime time.Time, cached bool, err error) {
+	cacheFilename = f.cacheFilename()
+
+	if f.revision != nil {
+		modTime, err = time.Parse(time.RFC3339, f.revision.ModifiedTime)
+		if err != nil {
+			return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to parse revision modtime: %w", err)
+		}
+		creationTime = modTime
+	} else {
+		modTime, err = time.Parse(time.RFC3339, f.file.ModifiedTime)
+		if err != nil {
+			return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to parse file modtime: %w", err)
+		}
+
+		creationTime, err = time.Parse(time.RFC3339, f.file.CreatedTime)
+		if err != nil {
+			return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to parse file modtime: %w", err)
 		}
-		return cacheFilename, modTime, creationTime, false, fmt.Errorf("failed to retrieve file info: %w", err)
 	}
 
-	return cacheFilename, modTime, creationTime, info.ModTime().Before(modTime), nil
+	cached, err = IsCached(cacheFilename, f.cacheRecord())
+	if err != nil {
+		return cacheFilename, modTime, creationTime, false, err
+	}
+	return cacheFilename, modTime, creationTime, cached, nil
 }
 
 func (f *File) HttpClient(ctx context.Context) (client *http.Client) {
 	if f.drive != nil {
-		return f.config.HttpClientProviderFunc(ctx, f.config.AdministratorSubject)
+		return f.config.HttpClientProviderFunc(ctx, f.config.SharedDriveSubject(ctx, f.drive.Id))
 	}
 	return f.config.HttpClientProviderFunc(ctx, f.user.PrimaryEmail)
 }
@@ -104,6 +200,113 @@ func (f *File) downloadFile(ctx context.Context, logger *slog.Logger) (cacheFile
 		return cacheFilename, nil
 	}
 
+	logger.Debug("Waiting for download")
+	err = downloads.Do(ctx, f.downloadKey(), func(ctx context.Context) error {
+		return f.fetchFile(ctx, logger, cacheFilename, modTime)
+	})
+	if err != nil {
+		return "", err
+	}
+	return cacheFilename, nil
+}
+
+// Identifies the download of the contents described by the record. Every
+// version of a file shares the cache file but never the download
+func (f *File) downloadKey() (key string) {
+	record := f.cacheRecord()
+	return strings.Join([]string{
+		record.FileId,
+		strconv.FormatInt(record.Version, 10),
+		record.HeadRevisionId,
+		record.RevisionId,
+		record.ExportMimeType,
+	}, "/")
+}
+
+// Opens the cached contents, downloading them when missing. Another version
+// may replace the cache file meanwhile, so the record is checked again once
+// the file is open
+func (f *File) openFile(ctx context.Context, logger *slog.Logger, flags uint32) (fd int, err error) {
+	for attempt := 1; ; attempt++ {
+		var filename string
+		filename, err = f.downloadFile(ctx, logger)
+		if err != nil {
+			return -1, err
+		}
+
+		logger.Debug("Openning file")
+		fd, err = unix.Open(filename, 0, flags)
+		if err != nil {
+			return -1, err
+		}
+
+		var cached bool
+		cached, err = IsCached(filename, f.cacheRecord())
+		if err == nil && cached {
+			return fd, nil
+		}
+		unix.Close(fd)
+		if err != nil {
+			return -1, err
+		}
+
+		logger.Debug("Cache file replaced by another version", "attempt", attempt)
+		if attempt >= MaxDownloadAttempts {
+			return -1, ErrReplaced
+		}
+	}
+}
+
+// Pulls the remote contents into the cache file. Contents are written to a
+// temporary file renamed into place once complete so readers never see a
+// partial copy
+func (f *File) fetchFile(ctx context.Context, logger *slog.Logger, cacheFilename string, modTime time.Time) (err error) {
+	// A previous download may have finished while waiting
+	cached, err := IsCached(cacheFilename, f.cacheRecord())
+	if err != nil {
+		return err
+	}
+	if cached {
+		logger.Debug("File already cached")
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		var status string
+		status, err = f.pullFile(ctx, logger, cacheFilename, modTime)
+		if err == nil {
+			Verifications.Add(status, 1)
+			logger.Info("Download finished", "verification", status, "attempt", attempt)
+			return nil
+		}
+		if !errors.Is(err, ErrChecksumMismatch) {
+			return err
+		}
+		Verifications.Add(status, 1)
+
+		logger.Warn("Downloaded contents don't match the checksum", "attempt", attempt, "error-msg", err)
+		if attempt >= MaxDownloadAttempts {
+			return err
+		}
+	}
+}
+
+// Checksums of the remote contents. Exports have none
+func (f *File) checksums() (md5Checksum, sha256Checksum string) {
+	switch {
+	case f.exportMimeType != "":
+		return "", ""
+	case f.revision != nil:
+		return f.revision.Md5Checksum, ""
+	default:
+		return f.file.Md5Checksum, f.file.Sha256Checksum
+	}
+}
+
+// Downloads the contents once and verifies them against the remote checksums
+func (f *File) pullFile(ctx context.Context, logger *slog.Logger, cacheFilename string, modTime time.Time) (status string, err error) {
+	status = Unverified
+
 	logger.Debug("Pulling from remote")
 
 	client := f.HttpClient(ctx)
@@ -111,29 +314,51 @@ func (f *File) downloadFile(ctx context.Context, logger *slog.Logger) (cacheFile
 	logger.Debug("Preparing drive service")
 	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		return "", fmt.Errorf("failed to prepare drive service: %w", err)
+		return status, fmt.Errorf("failed to prepare drive service: %w", err)
 	}
 
 	logger.Debug("Downloading file", "mime-type", f.file.MimeType)
 	var download *http.Response
-	if strings.Contains(f.file.MimeType, "google") {
-		var targetMime string
-		for mime := range f.file.ExportLinks {
-			if strings.Contains(mime, "officedocument") {
-				targetMime = mime
-				break
-			}
-			targetMime = mime
+	switch {
+	case f.revision != nil && f.exportMimeType != "":
+		exportLink, found := f.revision.ExportLinks[f.exportMimeType]
+		if !found {
+			return status, fmt.Errorf("revision can't be exported: %s", f.exportMimeType)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, exportLink, nil)
+		if err != nil {
+			return status, fmt.Errorf("failed to prepare revision export: %w", err)
+		}
+		download, err = client.Do(req)
+		if err != nil {
+			return status, fmt.Errorf("failed to export revision contents: %s: %w", f.exportMimeType, err)
+		}
+		defer download.Body.Close()
+
+		if download.StatusCode != http.StatusOK {
+			return status, fmt.Errorf("failed to export revision contents: %s: %s", f.exportMimeType, download.Status)
+		}
+	case f.revision != nil:
+		download, err = driveSvc.Revisions.
+			Get(f.file.Id, f.revision.Id).
+			AcknowledgeAbuse(true).
+			Context(ctx).
+			Download()
+		if err != nil {
+			return status, fmt.Errorf("failed to download revision: %w", err)
 		}
+		defer download.Body.Close()
+	case f.exportMimeType != "":
 		download, err = driveSvc.Files.
-			Export(f.file.Id, targetMime).
+			Export(f.file.Id, f.exportMimeType).
 			Context(ctx).
 			Download()
 		if err != nil {
-			return "", fmt.Errorf("failed to export file contents: %s: %w", targetMime, err)
+			return status, fmt.Errorf("failed to export file contents: %s: %w", f.exportMimeType, err)
 		}
 		defer download.Body.Close()
-	} else {
+	default:
 		download, err = driveSvc.Files.
 			Get(f.file.Id).
 			SupportsAllDrives(true).
@@ -142,7 +367,7 @@ func (f *File) downloadFile(ctx context.Context, logger *slog.Logger) (cacheFile
 			Context(ctx).
 			Download()
 		if err != nil {
-			return "", fmt.Errorf("failed to download file: %w", err)
+			return status, fmt.Errorf("failed to download file: %w", err)
 		}
 		defer download.Body.Close()
 	}
@@ -150,62 +375,220 @@ func (f *File) downloadFile(ctx context.Context, logger *slog.Logger) (cacheFile
 	logger.Debug("Saving file in cache")
 	srcBuffer := bufio.NewReader(download.Body)
 
-	logger.Debug("Creating file")
-	file, err := os.Create(cacheFilename)
+	logger.Debug("Creating temporary file")
+	file, err := os.CreateTemp(path.Dir(cacheFilename), path.Base(cacheFilename)+".*"+PartialSuffix)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return status, fmt.Errorf("failed to create file: %w", err)
 	}
+	tmpFilename := file.Name()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpFilename)
+		}
+	}()
 	dstBuffer := bufio.NewWriter(file)
 
 	logger.Debug("Copying contents")
-	_, err = io.Copy(dstBuffer, srcBuffer)
+	verifier := newVerifier(f.checksums())
+	_, err = io.Copy(io.MultiWriter(dstBuffer, verifier), srcBuffer)
 	if err != nil {
-		return "", fmt.Errorf("failed to copy contents: %w", err)
+		return status, fmt.Errorf("failed to copy contents: %w", err)
 	}
 	logger.Debug("Flushing missing data")
 	err = dstBuffer.Flush()
 	if err != nil {
-		return "", fmt.Errorf("failed to flush contents: %w", err)
+		return status, fmt.Errorf("failed to flush contents: %w", err)
+	}
+
+	logger.Debug("Verifying contents")
+	status, err = verifier.Verify()
+	if err != nil {
+		return status, err
+	}
+
+	logger.Debug("Syncing file")
+	err = file.Sync()
+	if err != nil {
+		return status, fmt.Errorf("failed to sync file: %w", err)
 	}
 
 	logger.Debug("Closing file")
 	err = file.Close()
 	if err != nil {
-		return "", fmt.Errorf("failed to close file: %w", err)
+		return status, fmt.Errorf("failed to close file: %w", err)
 	}
 
 	logger.Debug("Updating mod time")
-	err = os.Chtimes(cacheFilename, time.Now(), modTime)
+	err = os.Chtimes(tmpFilename, time.Now(), modTime)
+	if err != nil {
+		return status, fmt.Errorf("failed to change modify time to local cache: %w", err)
+	}
+
+	// Downloads of other versions never leave their record next to these
+	// contents
+	installMu.Lock()
+	defer installMu.Unlock()
+
+	logger.Debug("Invalidating previous copy")
+	err = RemoveRecord(cacheFilename)
 	if err != nil {
-		return "", fmt.Errorf("failed to change modify time to local cache: %w", err)
+		return status, err
 	}
 
+	logger.Debug("Moving file into place")
+	err = os.Rename(tmpFilename, cacheFilename)
+	if err != nil {
+		return status, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	logger.Debug("Writing record")
+	err = WriteRecord(cacheFilename, f.cacheRecord())
+	if err != nil {
+		return status, err
+	}
+	f.config.CacheManager.Update(cacheFilename)
+
 	logger.Debug("File saved")
-	return cacheFilename, nil
+	return status, nil
 }
 
-func (f *File) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
-	logger := f.logger.With("action", "Open")
-	filename, err := f.downloadFile(ctx, logger)
+// Reports if the contents are read by blocks instead of downloading the file
+func (f *File) streamed() (ok bool) {
+	return !f.config.Cache.FullDownload && f.exportMimeType == "" && f.revision == nil && f.file.Size > 0
+}
+
+func (f *File) fetchRange(ctx context.Context, start, end int64) (body io.ReadCloser, err error) {
+	client := f.HttpClient(ctx)
+
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare drive service: %w", err)
+	}
+
+	call := driveSvc.Files.
+		Get(f.file.Id).
+		SupportsAllDrives(true).
+		SupportsTeamDrives(true).
+		AcknowledgeAbuse(true).
+		Context(ctx)
+	call.Header().Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	download, err := call.Download()
 	if err != nil {
-		logger.Error("Failed to download file", "filename", filename, "error-msg", err)
-		return nil, 0, fs.ToErrno(err)
+		return nil, fmt.Errorf("failed to download range: %w", err)
+	}
+
+	if download.StatusCode != http.StatusPartialContent && start > 0 {
+		// Range ignored by the server, the whole file is returned
+		_, err = io.CopyN(io.Discard, download.Body, start)
+		if err != nil {
+			download.Body.Close()
+			return nil, fmt.Errorf("failed to skip to range start: %w", err)
+		}
+	}
+	return download.Body, nil
+}
+
+// Returns the block cache of the current version of the file
+func (f *File) blockCache() (blocks *BlockCache, err error) {
+	f.blocksMu.Lock()
+	defer f.blocksMu.Unlock()
+
+	if f.blocks != nil {
+		_, err = os.Stat(f.blocks.filename)
+		if os.IsNotExist(err) {
+			// Evicted from the cache since the last open
+			f.blocks = nil
+		}
+	}
+
+	if f.blocks == nil {
+		md5Checksum, sha256Checksum := f.checksums()
+		f.blocks, err = NewBlockCache(f.blocksFilename(), f.file.Size, f.config.Cache.BlockSize, md5Checksum, sha256Checksum)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return f.blocks, nil
+}
+
+func (f *File) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
+	logger := f.logger.With("action", "Open")
+
+	logger.Debug("Protecting contents from eviction")
+	key := f.cacheKey()
+	f.config.CacheManager.Acquire(key)
+	defer func() {
+		if errno != fs.OK {
+			f.config.CacheManager.Release(key)
+		}
+	}()
+
+	if f.streamed() {
+		logger.Debug("Streaming contents")
+		blocks, err := f.blockCache()
+		if err != nil {
+			logger.Error("Failed to prepare block cache", "error-msg", err)
+			return nil, 0, httputils.ToErrno(err)
+		}
 
-	logger.Debug("Openning file")
-	fd, err := unix.Open(filename, 0, flags)
+		fh, err = NewFileReader(logger, blocks, f.fetchRange, f.config.Cache.ReadAheadBlocks, f.config.CacheManager, key)
+		if err != nil {
+			logger.Error("Failed to open blocks file", "error-msg", err)
+			return nil, 0, httputils.ToErrno(err)
+		}
+		return fh, f.openFlags(), fs.OK
+	}
+	fd, err := f.openFile(ctx, logger, flags)
 	if err != nil {
-		logger.Error("Failed to open file", "error-msg", err)
-		return nil, 0, fs.ToErrno(err)
+		logger.Error("Failed to open file", "error-msg", err, "error-reason", httputils.Reason(err))
+		if errors.Is(err, ErrChecksumMismatch) || errors.Is(err, ErrReplaced) {
+			return nil, 0, syscall.EIO
+		}
+		return nil, 0, httputils.ToErrno(err)
 	}
 
 	fh = fs.NewLoopbackFile(fd)
-	return fh, 0, fs.OK
+	return fh, f.openFlags(), fs.OK
+}
+
+// Files remembered by every generation of openedVersions
+const MaxOpenedVersions = 1 << 16
+
+// Version last opened of every file, export format and revision. Dropped
+// files are only reopened without the page cache
+var openedVersions = boundedmap.New[string, int64](MaxOpenedVersions)
+
+// Lets the kernel keep the pages of the previous open when the version did
+// not change since then
+func (f *File) openFlags() (fuseFlags uint32) {
+	key := f.file.Id + "/" + f.exportMimeType
+	if f.revision != nil {
+		key += "/" + f.revision.Id
+	}
+	previous, loaded := openedVersions.Swap(key, f.file.Version)
+	if loaded && previous == f.file.Version {
+		return fuse.FOPEN_KEEP_CACHE
+	}
+	return 0
+}
+
+func (f *File) Release(ctx context.Context, fh fs.FileHandle) (errno syscall.Errno) {
+	logger := f.logger.With("action", "Release")
+
+	if fr, ok := fh.(fs.FileReleaser); ok {
+		errno = fr.Release(ctx)
+	}
+
+	logger.Debug("Allowing eviction of contents")
+	f.config.CacheManager.Release(f.cacheKey())
+	return errno
 }
 
 func (f *File) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) (errno syscall.Errno) {
 	logger := f.logger.With("action", "Getattr")
 
+	f.config.Timeouts.Files.AttrOut(out)
 	if fh != nil {
 		logger.Debug("Checking file handle")
 		if fga, ok := fh.(fs.FileGetattrer); ok {
@@ -220,7 +603,7 @@ func (f *File) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut)
 	filename, modTime, creationTime, cached, err := f.fileInfo()
 	if err != nil {
 		logger.Error("failed to get file information", "error-msg", err)
-		return fs.ToErrno(err)
+		return httputils.ToErrno(err)
 	}
 
 	var stat syscall.Stat_t
@@ -228,12 +611,16 @@ func (f *File) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut)
 		err = syscall.Lstat(filename, &stat)
 		if err != nil {
 			logger.Error("Failed to get file Lstat", "error-msg", err)
-			return fs.ToErrno(err)
+			return httputils.ToErrno(err)
 		}
 	} else {
+		size := f.file.Size
+		if f.revision != nil {
+			size = f.revision.Size
+		}
 		stat = syscall.Stat_t{
 			Mode: syscall.S_IFREG,
-			Size: f.file.Size,
+			Size: size,
 			Atim: syscall.NsecToTimespec(modTime.UnixNano()),
 			Mtim: syscall.NsecToTimespec(modTime.UnixNano()),
 			Ctim: syscall.NsecToTimespec(creationTime.UnixNano()),
@@ -245,3 +632,11 @@ func (f *File) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut)
 
 	return fs.OK
 }
+
+func (f *File) Getxattr(ctx context.Context, attr string, dest []byte) (size uint32, errno syscall.Errno) {
+	return xattrs.Getxattr(f.file, attr, dest)
+}
+
+func (f *File) Listxattr(ctx context.Context, dest []byte) (size uint32, errno syscall.Errno) {
+	return xattrs.Listxattr(f.file, dest)
+}
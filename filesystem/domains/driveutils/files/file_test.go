@@ -0,0 +1,24 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+func TestFile_openFlags(t *testing.T) {
+	assertions := assert.New(t)
+
+	file := &drive.File{Id: "openFlags", Version: 1}
+	assertions.Zero((&File{file: file}).openFlags(), "first open")
+	assertions.Equal(uint32(fuse.FOPEN_KEEP_CACHE), (&File{file: file}).openFlags(), "same version")
+	assertions.Zero((&File{file: file, exportMimeType: "application/pdf"}).openFlags(), "other export")
+	assertions.Zero((&File{file: file, revision: &drive.Revision{Id: "0B-old"}}).openFlags(), "other revision")
+
+	newer := *file
+	newer.Version = 2
+	assertions.Zero((&File{file: &newer}).openFlags(), "newer version")
+	assertions.Equal(uint32(fuse.FOPEN_KEEP_CACHE), (&File{file: &newer}).openFlags(), "newer version reopened")
+}
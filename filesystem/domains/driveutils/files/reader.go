@@ -0,0 +1,119 @@
+package files
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/diskcache"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+)
+
+// FileReader is a file handle downloading on demand the blocks read, with
+// read-ahead of the following blocks on sequential reads
+type FileReader struct {
+	mu         sync.Mutex
+	nextOffset int64
+
+	// Cancels the read-ahead still running on Release
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	file      *os.File
+	blocks    *BlockCache
+	fetch     RangeFetcher
+	readAhead int64
+	manager   *diskcache.Manager
+	key       string
+	logger    *slog.Logger
+}
+
+// NewFileReader opens the blocks file. The manager key is acquired by every
+// read-ahead so the blocks are never evicted under it
+func NewFileReader(logger *slog.Logger, blocks *BlockCache, fetch RangeFetcher, readAhead int64, manager *diskcache.Manager, key string) (r *FileReader, err error) {
+	file, err := os.Open(blocks.filename)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &FileReader{
+		ctx:       ctx,
+		cancel:    cancel,
+		file:      file,
+		blocks:    blocks,
+		fetch:     fetch,
+		readAhead: readAhead,
+		manager:   manager,
+		key:       key,
+		logger:    logger,
+	}, nil
+}
+
+var (
+	_ fs.FileReader   = (*FileReader)(nil)
+	_ fs.FileReleaser = (*FileReader)(nil)
+)
+
+func (r *FileReader) Read(ctx context.Context, dest []byte, off int64) (result fuse.ReadResult, errno syscall.Errno) {
+	logger := r.logger.With("action", "Read", "offset", off, "length", len(dest))
+
+	if off >= r.blocks.size || len(dest) == 0 {
+		return fuse.ReadResultData(nil), fs.OK
+	}
+	end := min(off+int64(len(dest)), r.blocks.size)
+
+	r.mu.Lock()
+	sequential := off == r.nextOffset
+	r.nextOffset = end
+	r.mu.Unlock()
+
+	first := off / r.blocks.blockSize
+	last := (end - 1) / r.blocks.blockSize
+	filled, err := r.blocks.Ensure(ctx, r.fetch, first, last)
+	if filled {
+		// The blocks file grows while open, the limits are enforced on the
+		// other files
+		r.manager.Update(r.key)
+	}
+	if err != nil {
+		logger.Error("Failed to download blocks", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	if sequential && r.readAhead > 0 {
+		// Detached from the request, which returns before it finishes. Lives
+		// until the handle is released
+		r.manager.Acquire(r.key)
+		go func() {
+			defer r.manager.Release(r.key)
+
+			filled, err := r.blocks.Ensure(r.ctx, r.fetch, last+1, last+r.readAhead)
+			if filled {
+				r.manager.Update(r.key)
+			}
+			if err != nil {
+				logger.Debug("Failed to read ahead", "error-msg", err)
+			}
+		}()
+	}
+
+	n, err := r.file.ReadAt(dest[:end-off], off)
+	if err != nil && int64(n) != end-off {
+		logger.Error("Failed to read blocks", "error-msg", err)
+		return nil, httputils.ToErrno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), fs.OK
+}
+
+func (r *FileReader) Release(ctx context.Context) (errno syscall.Errno) {
+	r.cancel()
+	err := r.file.Close()
+	if err != nil {
+		return httputils.ToErrno(err)
+	}
+	return fs.OK
+}
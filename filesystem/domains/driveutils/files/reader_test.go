@@ -0,0 +1,59 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/diskcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileReader_ReadUpdatesCache(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := t.TempDir()
+	manager, err := diskcache.New(slog.New(slog.DiscardHandler), diskcache.Config{
+		Path:     path,
+		MaxSize:  96 << 10,
+		Sidecars: []string{BlocksSuffix, BlockMapSuffix},
+	})
+	if !assertions.Nil(err, "failed to create manager") {
+		return
+	}
+
+	other := filepath.Join(path, "other")
+	err = os.WriteFile(other, make([]byte, 64<<10), 0o600)
+	if !assertions.Nil(err, "failed to write other file") {
+		return
+	}
+	manager.Update(other)
+
+	contents := bytes.Repeat([]byte("0123456789abcdef"), 4<<10)
+	var requests atomic.Int64
+	filename := filepath.Join(path, "1AbCdEf")
+	blocks, err := NewBlockCache(filename, int64(len(contents)), 16<<10, "", "")
+	if !assertions.Nil(err, "failed to create block cache") {
+		return
+	}
+	manager.Acquire(filename)
+	reader, err := NewFileReader(slog.New(slog.DiscardHandler), blocks, rangeFetcher(contents, &requests), 0, manager, filename)
+	if !assertions.Nil(err, "failed to open reader") {
+		return
+	}
+	defer reader.Release(context.Background())
+
+	_, errno := reader.Read(context.Background(), make([]byte, len(contents)), 0)
+	assertions.Equal(fs.OK, errno, "read")
+
+	// The blocks filled while open are counted before they are released
+	_, err = os.Stat(other)
+	assertions.ErrorIs(err, os.ErrNotExist, "other file evicted")
+	_, err = os.Stat(filename + BlocksSuffix)
+	assertions.Nil(err, "open blocks kept")
+}
@@ -0,0 +1,103 @@
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Suffix of the sidecar file holding the record of a cached file
+const RecordSuffix = ".record.json"
+
+// CacheRecord describes the remote contents stored in a cache file. A cached
+// copy is only valid while its record matches the one of the remote file
+type CacheRecord struct {
+	FileId         string `json:"file-id"`
+	Version        int64  `json:"version"`
+	HeadRevisionId string `json:"head-revision-id,omitempty"`
+	Md5Checksum    string `json:"md5-checksum,omitempty"`
+	Sha256Checksum string `json:"sha256-checksum,omitempty"`
+	ExportMimeType string `json:"export-mime-type,omitempty"`
+	RevisionId     string `json:"revision-id,omitempty"`
+}
+
+// ReadRecord returns the record of the cache file, nil when missing
+func ReadRecord(cacheFilename string) (record *CacheRecord, err error) {
+	contents, err := os.ReadFile(cacheFilename + RecordSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	record = new(CacheRecord)
+	err = json.Unmarshal(contents, record)
+	if err != nil {
+		// A corrupted record invalidates the copy
+		return nil, nil
+	}
+	return record, nil
+}
+
+// WriteRecord stores the record of the cache file
+func WriteRecord(cacheFilename string, record *CacheRecord) (err error) {
+	contents, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	err = os.WriteFile(cacheFilename+RecordSuffix, contents, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+// RemoveRecord invalidates the cache file
+func RemoveRecord(cacheFilename string) (err error) {
+	err = os.Remove(cacheFilename + RecordSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove record: %w", err)
+	}
+	return nil
+}
+
+// IsCached reports if the cache file holds the contents described by the
+// record
+func IsCached(cacheFilename string, expected *CacheRecord) (cached bool, err error) {
+	_, err = os.Stat(cacheFilename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to retrieve file info: %w", err)
+	}
+
+	record, err := ReadRecord(cacheFilename)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && *record == *expected, nil
+}
+
+// InvalidateContents forces the download of every cached copy of the file.
+// Revisions never change and are kept
+func InvalidateContents(cachePath, fileId string) (err error) {
+	records, err := filepath.Glob(filepath.Join(cachePath, fileId+".*"+RecordSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to find records: %w", err)
+	}
+	records = append(records, filepath.Join(cachePath, fileId+RecordSuffix))
+
+	for _, record := range records {
+		err = RemoveRecord(strings.TrimSuffix(record, RecordSuffix))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCached(t *testing.T) {
+	expected := CacheRecord{
+		FileId:         "1AbCdEf",
+		Version:        7,
+		HeadRevisionId: "0B-revision",
+		Md5Checksum:    "d41d8cd98f00b204e9800998ecf8427e",
+	}
+
+	type Test struct {
+		Name string
+		// Record written next to the cache file, none when nil
+		Record *CacheRecord
+		// Write the cache file itself
+		Contents bool
+		Cached   bool
+	}
+	tests := []Test{
+		{
+			Name:     "Fresh",
+			Record:   &expected,
+			Contents: true,
+			Cached:   true,
+		},
+		{
+			Name: "Stale version",
+			Record: func() *CacheRecord {
+				record := expected
+				record.Version = 6
+				return &record
+			}(),
+			Contents: true,
+		},
+		{
+			Name: "Stale md5",
+			Record: func() *CacheRecord {
+				record := expected
+				record.Md5Checksum = "0cc175b9c0f1b6a831c399e269772661"
+				return &record
+			}(),
+			Contents: true,
+		},
+		{
+			Name: "Stale head revision",
+			Record: func() *CacheRecord {
+				record := expected
+				record.HeadRevisionId = "0B-previous"
+				return &record
+			}(),
+			Contents: true,
+		},
+		{
+			Name:     "Missing record",
+			Contents: true,
+		},
+		{
+			Name:   "Missing contents",
+			Record: &expected,
+		},
+		{
+			Name: "Missing cache",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			cacheFilename := filepath.Join(t.TempDir(), expected.FileId)
+			if test.Contents {
+				err := os.WriteFile(cacheFilename, []byte("contents"), 0o600)
+				if !assertions.Nil(err, "failed to write contents") {
+					return
+				}
+			}
+			if test.Record != nil {
+				err := WriteRecord(cacheFilename, test.Record)
+				if !assertions.Nil(err, "failed to write record") {
+					return
+				}
+			}
+
+			cached, err := IsCached(cacheFilename, &expected)
+			if !assertions.Nil(err, "failed to check cache") {
+				return
+			}
+			assertions.Equal(test.Cached, cached, "cached")
+		})
+	}
+}
+
+func TestReadRecord(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		cacheFilename := filepath.Join(t.TempDir(), "1AbCdEf")
+		expected := &CacheRecord{FileId: "1AbCdEf", Version: 3, ExportMimeType: "application/pdf"}
+		err := WriteRecord(cacheFilename, expected)
+		if !assertions.Nil(err, "failed to write record") {
+			return
+		}
+
+		record, err := ReadRecord(cacheFilename)
+		if !assertions.Nil(err, "failed to read record") {
+			return
+		}
+		assertions.Equal(expected, record, "record")
+	})
+	t.Run("Missing", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		record, err := ReadRecord(filepath.Join(t.TempDir(), "1AbCdEf"))
+		assertions.Nil(err, "failed to read record")
+		assertions.Nil(record, "record")
+	})
+	t.Run("Corrupted", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		cacheFilename := filepath.Join(t.TempDir(), "1AbCdEf")
+		err := os.WriteFile(cacheFilename+RecordSuffix, []byte("{"), 0o600)
+		if !assertions.Nil(err, "failed to write record") {
+			return
+		}
+
+		record, err := ReadRecord(cacheFilename)
+		assertions.Nil(err, "failed to read record")
+		assertions.Nil(record, "record")
+	})
+}
+
+func TestInvalidateContents(t *testing.T) {
+	assertions := assert.New(t)
+
+	cachePath := t.TempDir()
+	record := &CacheRecord{FileId: "1AbCdEf"}
+	cacheFilenames := []string{
+		filepath.Join(cachePath, "1AbCdEf"),
+		filepath.Join(cachePath, "1AbCdEf.application-pdf"),
+	}
+	for _, cacheFilename := range cacheFilenames {
+		err := WriteRecord(cacheFilename, record)
+		if !assertions.Nil(err, "failed to write record") {
+			return
+		}
+	}
+
+	err := InvalidateContents(cachePath, "1AbCdEf")
+	if !assertions.Nil(err, "failed to invalidate contents") {
+		return
+	}
+
+	for _, cacheFilename := range cacheFilenames {
+		record, err := ReadRecord(cacheFilename)
+		assertions.Nil(err, "failed to read record")
+		assertions.Nil(record, "record of %s", cacheFilename)
+	}
+}
@@ -0,0 +1,74 @@
+package files
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Times a download is attempted before giving up on a checksum mismatch
+const MaxDownloadAttempts = 3
+
+// Verification status of a download
+const (
+	Verified   = "verified"
+	Mismatch   = "mismatch"
+	Unverified = "unverified"
+)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Downloads by verification status, served under /debug/vars with --debug-addr
+var Verifications = expvar.NewMap("gsuitefs_verifications")
+
+// Hashes downloaded contents to compare them against the remote checksums
+type verifier struct {
+	md5Checksum    string
+	sha256Checksum string
+	md5            hash.Hash
+	sha256         hash.Hash
+	writer         io.Writer
+}
+
+func newVerifier(md5Checksum, sha256Checksum string) (v *verifier) {
+	v = &verifier{
+		md5Checksum:    md5Checksum,
+		sha256Checksum: sha256Checksum,
+		md5:            md5.New(),
+		sha256:         sha256.New(),
+	}
+	v.writer = io.MultiWriter(v.md5, v.sha256)
+	return v
+}
+
+func (v *verifier) Write(p []byte) (n int, err error) {
+	return v.writer.Write(p)
+}
+
+// Verify compares the hashed contents against every known checksum.
+// Exported documents have no checksum and are reported as unverified
+func (v *verifier) Verify() (status string, err error) {
+	if v.md5Checksum == "" && v.sha256Checksum == "" {
+		return Unverified, nil
+	}
+
+	if v.md5Checksum != "" {
+		sum := hex.EncodeToString(v.md5.Sum(nil))
+		if sum != v.md5Checksum {
+			return Mismatch, fmt.Errorf("%w: md5 expected %s got %s", ErrChecksumMismatch, v.md5Checksum, sum)
+		}
+	}
+
+	if v.sha256Checksum != "" {
+		sum := hex.EncodeToString(v.sha256.Sum(nil))
+		if sum != v.sha256Checksum {
+			return Mismatch, fmt.Errorf("%w: sha256 expected %s got %s", ErrChecksumMismatch, v.sha256Checksum, sum)
+		}
+	}
+	return Verified, nil
+}
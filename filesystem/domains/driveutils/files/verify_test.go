@@ -0,0 +1,162 @@
+package files
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Downloads counted with the verification status
+func verifications(status string) (count int64) {
+	value, ok := Verifications.Get(status).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return value.Value()
+}
+
+func checksums(contents []byte) (md5Checksum, sha256Checksum string) {
+	md5Sum := md5.Sum(contents)
+	sha256Sum := sha256.Sum256(contents)
+	return hex.EncodeToString(md5Sum[:]), hex.EncodeToString(sha256Sum[:])
+}
+
+func TestVerifier(t *testing.T) {
+	contents := []byte("0123456789abcdef")
+	md5Checksum, sha256Checksum := checksums(contents)
+
+	type Test struct {
+		Name           string
+		Md5Checksum    string
+		Sha256Checksum string
+		Status         string
+		Mismatch       bool
+	}
+	tests := []Test{
+		{Name: "Match", Md5Checksum: md5Checksum, Sha256Checksum: sha256Checksum, Status: Verified},
+		{Name: "Md5 only", Md5Checksum: md5Checksum, Status: Verified},
+		{Name: "Md5 mismatch", Md5Checksum: strings.Repeat("0", 32), Sha256Checksum: sha256Checksum, Status: Mismatch, Mismatch: true},
+		{Name: "Sha256 mismatch", Md5Checksum: md5Checksum, Sha256Checksum: strings.Repeat("0", 64), Status: Mismatch, Mismatch: true},
+		{Name: "No checksums", Status: Unverified},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			verifier := newVerifier(test.Md5Checksum, test.Sha256Checksum)
+			_, err := verifier.Write(contents)
+			if !assertions.Nil(err, "failed to write contents") {
+				return
+			}
+
+			status, err := verifier.Verify()
+			assertions.Equal(test.Status, status, "status")
+			if test.Mismatch {
+				assertions.ErrorIs(err, ErrChecksumMismatch, "verify")
+			} else {
+				assertions.Nil(err, "verify")
+			}
+		})
+	}
+}
+
+func TestBlockCache_EnsureVerified(t *testing.T) {
+	assertions := assert.New(t)
+
+	contents := []byte("0123456789abcdef")
+	md5Checksum, sha256Checksum := checksums(contents)
+	var requests atomic.Int64
+	fetch := rangeFetcher(contents, &requests)
+
+	blocks, err := NewBlockCache(filepath.Join(t.TempDir(), "1AbCdEf"), int64(len(contents)), 4, md5Checksum, sha256Checksum)
+	if !assertions.Nil(err, "failed to create block cache") {
+		return
+	}
+
+	verified := verifications(Verified)
+	// Incomplete contents are never verified
+	filled, err := blocks.Ensure(context.Background(), fetch, 0, 1)
+	assertions.Nil(err, "failed to ensure first blocks")
+	assertions.True(filled, "filled")
+	assertions.Equal(verified, verifications(Verified), "verified incomplete")
+
+	_, err = blocks.Ensure(context.Background(), fetch, 2, 3)
+	assertions.Nil(err, "failed to ensure last blocks")
+	assertions.Equal(verified+1, verifications(Verified), "verified")
+
+	// Verified once
+	filled, err = blocks.Ensure(context.Background(), fetch, 0, 3)
+	assertions.Nil(err, "failed to ensure blocks")
+	assertions.False(filled, "filled")
+	assertions.Equal(verified+1, verifications(Verified), "verified again")
+}
+
+func TestBlockCache_EnsureMismatch(t *testing.T) {
+	contents := []byte("0123456789abcdef")
+	md5Checksum, sha256Checksum := checksums(contents)
+	corrupted := []byte("0123456789abcdeX")
+
+	t.Run("Retried", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		// Corrupted on the first download only
+		var requests atomic.Int64
+		fetch := func(ctx context.Context, start, end int64) (body io.ReadCloser, err error) {
+			if requests.Load() == 0 {
+				return rangeFetcher(corrupted, &requests)(ctx, start, end)
+			}
+			return rangeFetcher(contents, &requests)(ctx, start, end)
+		}
+
+		blocks, err := NewBlockCache(filepath.Join(t.TempDir(), "1AbCdEf"), int64(len(contents)), 4, md5Checksum, sha256Checksum)
+		if !assertions.Nil(err, "failed to create block cache") {
+			return
+		}
+
+		mismatch, verified := verifications(Mismatch), verifications(Verified)
+		_, err = blocks.Ensure(context.Background(), fetch, 0, 3)
+		assertions.Nil(err, "failed to ensure blocks")
+		assertions.Equal(int64(2), requests.Load(), "requests")
+		assertions.Equal(mismatch+1, verifications(Mismatch), "mismatches")
+		assertions.Equal(verified+1, verifications(Verified), "verified")
+	})
+	t.Run("EIO", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		var requests atomic.Int64
+		fetch := rangeFetcher(corrupted, &requests)
+
+		filename := filepath.Join(t.TempDir(), "1AbCdEf")
+		blocks, err := NewBlockCache(filename, int64(len(contents)), 4, md5Checksum, sha256Checksum)
+		if !assertions.Nil(err, "failed to create block cache") {
+			return
+		}
+		reader, err := NewFileReader(slog.New(slog.DiscardHandler), blocks, fetch, 0, nil, filename+BlocksSuffix)
+		if !assertions.Nil(err, "failed to open reader") {
+			return
+		}
+		defer reader.Release(context.Background())
+
+		mismatch := verifications(Mismatch)
+		_, errno := reader.Read(context.Background(), make([]byte, len(contents)), 0)
+		assertions.Equal(syscall.EIO, errno, "read")
+		assertions.Equal(int64(MaxDownloadAttempts), requests.Load(), "requests")
+		assertions.Equal(mismatch+MaxDownloadAttempts, verifications(Mismatch), "mismatches")
+
+		// Never downloaded again
+		_, errno = reader.Read(context.Background(), make([]byte, 4), 0)
+		assertions.Equal(syscall.EIO, errno, "read again")
+		assertions.Equal(int64(MaxDownloadAttempts), requests.Load(), "requests")
+	})
+}
@@ -0,0 +1,220 @@
+package revisions
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/exports"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const NodeName = "revisions-node"
+
+// Suffix appended to a file name to reach its revisions
+const Suffix = ".revisions"
+
+const ReaddirCacheKey = 0
+
+// Fields requested for every revision
+const RevisionFields = "id,mimeType,modifiedTime,size,md5Checksum,exportLinks"
+
+// Layout of the modified time prefixing every revision name
+const TimeLayout = "20060102T150405Z"
+
+type Config struct {
+	Logger  *slog.Logger
+	Config  *config.Config
+	User    *admin.User
+	Drive   *drive.Drive
+	Trashed bool
+	File    *drive.File
+	// Export format of Google-native documents
+	ExportMimeType string
+}
+
+// Revisions lists the revisions of a file as read-only files named by their
+// modified time and revision ID
+type Revisions struct {
+	fs.Inode
+
+	lookupCache  cache.Cache[string, *drive.Revision]
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+
+	trashed        bool
+	file           *drive.File
+	exportMimeType string
+	user           *admin.User
+	drive          *drive.Drive
+	logger         *slog.Logger
+	config         *config.Config
+}
+
+func New(cfg *Config) (r *Revisions) {
+	return &Revisions{
+		logger:         cfg.Logger.With("inode", NodeName, "filename", cfg.File.Name, "file-id", cfg.File.Id),
+		config:         cfg.Config,
+		user:           cfg.User,
+		drive:          cfg.Drive,
+		trashed:        cfg.Trashed,
+		file:           cfg.File,
+		exportMimeType: cfg.ExportMimeType,
+	}
+}
+
+var (
+	_ fs.NodeLookuper  = (*Revisions)(nil)
+	_ fs.NodeReaddirer = (*Revisions)(nil)
+)
+
+func (r *Revisions) HttpClient(ctx context.Context) (client *http.Client) {
+	if r.drive != nil {
+		return r.config.HttpClientProviderFunc(ctx, r.config.SharedDriveSubject(ctx, r.drive.Id))
+	}
+	return r.config.HttpClientProviderFunc(ctx, r.user.PrimaryEmail)
+}
+
+// Extension shared by every revision
+func (r *Revisions) extension() (ext string) {
+	if r.exportMimeType != "" {
+		return exports.Extension(r.exportMimeType)
+	}
+	ext = path.Ext(r.file.Name)
+	if ext == r.file.Name {
+		return ""
+	}
+	return ext
+}
+
+func (r *Revisions) revisionName(revision *drive.Revision) (name string) {
+	modTime, err := time.Parse(time.RFC3339, revision.ModifiedTime)
+	if err != nil {
+		return names.Encode(revision.Id + r.extension())
+	}
+	return names.Encode(modTime.UTC().Format(TimeLayout) + "_" + revision.Id + r.extension())
+}
+
+func (r *Revisions) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := r.logger.With("action", "Lookup", "name", name)
+
+	_, revisionId, found := strings.Cut(strings.TrimSuffix(names.Decode(name), r.extension()), "_")
+	if !found || revisionId == "" {
+		logger.Debug("Not a revision filename")
+		return nil, syscall.ENOENT
+	}
+
+	logger.Debug("Loading revision")
+	revision, err := r.lookupCache.LoadOrFetch(ctx, name, r.config.Cache.Expiration, func(ctx context.Context) (revision *drive.Revision, err error) {
+		client := r.HttpClient(ctx)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		revision, err = driveSvc.Revisions.
+			Get(r.file.Id, revisionId).
+			Fields(RevisionFields).
+			Context(ctx).
+			Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+
+		if r.revisionName(revision) != name {
+			return nil, cache.Negative(syscall.ENOENT)
+		}
+
+		return revision, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve revision", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	r.config.Timeouts.Files.EntryOut(out)
+	attr := fs.StableAttr{Mode: syscall.S_IFREG, Ino: inodes.Ino(inodes.KindRevision, r.file.Id, revision.Id, r.exportMimeType)}
+	if node = inodes.Reuse(&r.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	cfg := files.Config{
+		Logger:         r.logger,
+		Config:         r.config,
+		User:           r.user,
+		Drive:          r.drive,
+		Trashed:        r.trashed,
+		File:           r.file,
+		ExportMimeType: r.exportMimeType,
+		Revision:       revision,
+	}
+	node = r.NewInode(ctx, files.New(&cfg), attr)
+	return node, fs.OK
+}
+
+func (r *Revisions) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := r.logger.With("action", "Readdir")
+
+	logger.Debug("Loading revision list")
+	dirEntries, err := r.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, r.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := r.HttpClient(ctx)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Pulling revision list")
+		err = driveSvc.Revisions.
+			List(r.file.Id).
+			Fields("nextPageToken,revisions("+RevisionFields+")").
+			PageSize(1_000).
+			Context(ctx).
+			Pages(ctx, func(rl *drive.RevisionList) (err error) {
+				logger.Debug("Retrieving page", "page-length", len(rl.Revisions))
+				for _, revision := range rl.Revisions {
+					name := r.revisionName(revision)
+					logger.Debug("Found revision", "name", name)
+					r.lookupCache.Store(name, revision, r.config.Cache.Expiration)
+					dirEntries = append(dirEntries, fuse.DirEntry{
+						Mode: syscall.S_IFREG,
+						Name: name,
+						Ino:  inodes.Ino(inodes.KindRevision, r.file.Id, revision.Id, r.exportMimeType),
+					})
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve revisions", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
@@ -0,0 +1,92 @@
+// Package xattrs exposes the Drive metadata of a file as extended attributes
+package xattrs
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Namespace of every attribute
+const Prefix = "user.gsuitefs."
+
+// Fields of the Drive file required by the attributes
+const Fields = "owners(emailAddress),webViewLink,md5Checksum,headRevisionId,lastModifyingUser(emailAddress),shared"
+
+type attribute struct {
+	name  string
+	value func(file *drive.File) string
+}
+
+var attributes = []attribute{
+	{"id", func(file *drive.File) string { return file.Id }},
+	{"mime-type", func(file *drive.File) string { return file.MimeType }},
+	{"owners", func(file *drive.File) string {
+		owners := make([]string, 0, len(file.Owners))
+		for _, owner := range file.Owners {
+			owners = append(owners, owner.EmailAddress)
+		}
+		return strings.Join(owners, ",")
+	}},
+	{"web-view-link", func(file *drive.File) string { return file.WebViewLink }},
+	{"md5-checksum", func(file *drive.File) string { return file.Md5Checksum }},
+	{"head-revision-id", func(file *drive.File) string { return file.HeadRevisionId }},
+	{"last-modifying-user", func(file *drive.File) string {
+		if file.LastModifyingUser == nil {
+			return ""
+		}
+		return file.LastModifyingUser.EmailAddress
+	}},
+	{"shared", func(file *drive.File) string { return strconv.FormatBool(file.Shared) }},
+}
+
+// Copies the value into dest following the getxattr(2) and listxattr(2)
+// conventions: an empty buffer only queries the size
+func copyOut(value []byte, dest []byte) (size uint32, errno syscall.Errno) {
+	if len(dest) == 0 {
+		return uint32(len(value)), 0
+	}
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	return uint32(copy(dest, value)), 0
+}
+
+// Getxattr copies the value of the attribute into dest
+func Getxattr(file *drive.File, attr string, dest []byte) (size uint32, errno syscall.Errno) {
+	name, found := strings.CutPrefix(attr, Prefix)
+	if !found || file == nil {
+		return 0, syscall.ENODATA
+	}
+
+	for _, a := range attributes {
+		if a.name != name {
+			continue
+		}
+		value := a.value(file)
+		if value == "" {
+			return 0, syscall.ENODATA
+		}
+		return copyOut([]byte(value), dest)
+	}
+	return 0, syscall.ENODATA
+}
+
+// Listxattr copies the NUL separated names of the available attributes into dest
+func Listxattr(file *drive.File, dest []byte) (size uint32, errno syscall.Errno) {
+	if file == nil {
+		return 0, 0
+	}
+
+	var list []byte
+	for _, a := range attributes {
+		if a.value(file) == "" {
+			continue
+		}
+		list = append(list, Prefix+a.name...)
+		list = append(list, 0)
+	}
+	return copyOut(list, dest)
+}
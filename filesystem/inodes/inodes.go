@@ -0,0 +1,100 @@
+// Package inodes derives stable inode numbers from the IDs of remote objects,
+// so the kernel sees the same inode every time an object is looked up. The
+// numbers are hashes, see Ino for the chance of two objects sharing one.
+package inodes
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/internal/boundedmap"
+)
+
+// Kinds of remote objects, every kind has its own ID namespace
+const (
+	KindDomain      = "domain"
+	KindUser        = "user"
+	KindGroup       = "group"
+	KindMember      = "member"
+	KindSharedDrive = "shared-drive"
+	KindDriveFile   = "drive-file"
+	KindLabel       = "label"
+	KindMessage     = "message"
+	KindOwner       = "owner"
+	KindRevision    = "revision"
+	KindRevisions   = "revisions"
+)
+
+// Set on every derived number so they never collide with the automatic
+// numbers handed out by go-fuse to persistent nodes
+const derivedBit = 1 << 63
+
+// Assignments kept by every generation of the tables. Every listed object is
+// assigned a number, so the tables are bounded instead of pruned on forget.
+// A dropped key gets the same number back unless its hash collides
+const MaxEntries = 1 << 20
+
+var (
+	mu sync.Mutex
+	// Inode number -> key owning it
+	owners = boundedmap.New[uint64, string](MaxEntries)
+	// Key -> inode number
+	numbers = boundedmap.New[string, uint64](MaxEntries)
+)
+
+func hash(key string, attempt int) (ino uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	if attempt > 0 {
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.Itoa(attempt)))
+	}
+	ino = h.Sum64() | derivedBit
+	if ino == ^uint64(0) {
+		// Reserved by go-fuse
+		ino--
+	}
+	return ino
+}
+
+// Ino returns the inode number of the remote object, a 63-bit FNV-1a hash of
+// the kind and IDs. Collisions between the keys kept in the tables are
+// resolved by hashing again, so they never share a number. A key dropped from
+// the tables may later get another number, or share one with a key it
+// collided with: among n objects the chance of any collision is about
+// n²/2^64, one in 16 million for MaxEntries objects
+func Ino(kind string, ids ...string) (ino uint64) {
+	key := kind
+	for _, id := range ids {
+		key += "/" + id
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ino, found := numbers.Load(key); found {
+		return ino
+	}
+
+	for attempt := 0; ; attempt++ {
+		ino = hash(key, attempt)
+		if owner, taken := owners.Load(ino); !taken || owner == key {
+			break
+		}
+	}
+	owners.Store(ino, key)
+	numbers.Store(key, ino)
+	return ino
+}
+
+// Reuse returns the known child of the parent when it has the same stable
+// attributes, meaning the remote object didn't change since it was added
+func Reuse(parent *fs.Inode, name string, attr fs.StableAttr) (child *fs.Inode) {
+	child = parent.GetChild(name)
+	if child == nil || child.StableAttr() != attr {
+		return nil
+	}
+	return child
+}
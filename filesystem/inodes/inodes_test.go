@@ -0,0 +1,61 @@
+package inodes
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIno(t *testing.T) {
+	t.Run("Stable", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ino := Ino(KindDriveFile, "stable")
+		assertions.Equal(ino, Ino(KindDriveFile, "stable"), "same key")
+		assertions.NotEqual(ino, Ino(KindSharedDrive, "stable"), "other kind")
+		assertions.NotEqual(ino, Ino(KindDriveFile, "stable", "application/pdf"), "other export")
+		assertions.NotZero(ino&derivedBit, "derived bit")
+	})
+	t.Run("Collision", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		// Claim the number of the key before it is assigned
+		key := KindDriveFile + "/collision"
+		mu.Lock()
+		owners.Store(hash(key, 0), "other")
+		mu.Unlock()
+
+		ino := Ino(KindDriveFile, "collision")
+		assertions.Equal(hash(key, 1), ino, "hashed again")
+		assertions.Equal(ino, Ino(KindDriveFile, "collision"), "same key")
+	})
+	t.Run("Unique", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		seen := map[uint64]string{}
+		for index := range 100_000 {
+			id := strconv.Itoa(index)
+			ino := Ino(KindMessage, id)
+			if other, found := seen[ino]; !assertions.False(found, "%s shares the number of %s", id, other) {
+				return
+			}
+			seen[ino] = id
+		}
+	})
+}
+
+func TestHash(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.NotEqual(hash("key", 0), hash("key", 1), "attempts")
+	assertions.NotEqual(^uint64(0), hash("key", 0), "reserved by go-fuse")
+}
+
+func TestReuse(t *testing.T) {
+	assertions := assert.New(t)
+
+	attr := fs.StableAttr{Ino: Ino(KindDriveFile, "reuse")}
+	assertions.Nil(Reuse(&fs.Inode{}, "child", attr), "unknown child")
+}
@@ -0,0 +1,128 @@
+// Package names encodes remote names into valid POSIX path components.
+//
+// Encoding escapes with %XX the characters that can't be part of a path
+// component ("/" and NUL), leading and trailing spaces and the "." and ".."
+// names. A "%" is only escaped when followed by two hex digits, so most names
+// containing it remain readable while Decode still reverses Encode.
+//
+// Names longer than MaxLength bytes are truncated and suffixed with a hash of
+// the remote name. Truncation can't be reversed by Decode, the remote name is
+// recovered from the bounded table of the last MaxTableEntries truncated
+// names. Dropped names are only resolved by listing their directory
+package names
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pluto-org-co/gsuitefs/internal/boundedmap"
+)
+
+// Maximum length in bytes of a path component
+const MaxLength = 255
+
+// Maximum length in bytes of an extension preserved on truncation
+const MaxExtensionLength = 32
+
+// Number of hex digits of the hash suffix of truncated names
+const HashLength = 8
+
+// Truncated names kept by every generation of the table
+const MaxTableEntries = 1 << 16
+
+// Truncated name -> remote name
+var table = boundedmap.New[string, string](MaxTableEntries)
+
+var truncatedRegexp = regexp.MustCompile(fmt.Sprintf(`~[0-9a-f]{%d}(\.[^.]*)?$`, HashLength))
+
+func isHex(c byte) (ok bool) {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func escape(remote string) (escaped string) {
+	switch remote {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	}
+
+	var (
+		builder strings.Builder
+		start   = len(remote) - len(strings.TrimLeft(remote, " "))
+		end     = len(strings.TrimRight(remote, " "))
+	)
+	builder.Grow(len(remote))
+	for index := 0; index < len(remote); index++ {
+		c := remote[index]
+		switch {
+		case c == '/', c == 0:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		case c == ' ' && (index < start || index >= end):
+			builder.WriteString("%20")
+		case c == '%' && index+2 < len(remote) && isHex(remote[index+1]) && isHex(remote[index+2]):
+			builder.WriteString("%25")
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+func truncate(remote, escaped string) (truncated string) {
+	ext := path.Ext(escaped)
+	if len(ext) > MaxExtensionLength || ext == escaped {
+		ext = ""
+	}
+
+	hash := sha256.Sum256([]byte(remote))
+	suffix := "~" + hex.EncodeToString(hash[:])[:HashLength] + ext
+
+	prefix := escaped[:MaxLength-len(suffix)]
+	for len(prefix) > 0 && !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix + suffix
+}
+
+// Encode returns the path component used for the remote name
+func Encode(remote string) (encoded string) {
+	encoded = escape(remote)
+	if len(encoded) > MaxLength {
+		encoded = truncate(remote, encoded)
+		table.Store(encoded, remote)
+	}
+	return encoded
+}
+
+// Decode returns the remote name of the path component. Names never returned
+// by Encode are unescaped
+func Decode(encoded string) (remote string) {
+	if remote, found := table.Load(encoded); found {
+		return remote
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(encoded))
+	for index := 0; index < len(encoded); index++ {
+		c := encoded[index]
+		if c == '%' && index+2 < len(encoded) && isHex(encoded[index+1]) && isHex(encoded[index+2]) {
+			value, _ := hex.DecodeString(encoded[index+1 : index+3])
+			builder.WriteByte(value[0])
+			index += 2
+			continue
+		}
+		builder.WriteByte(c)
+	}
+	return builder.String()
+}
+
+// IsTruncated reports if the path component may be a truncated remote name
+func IsTruncated(encoded string) (truncated bool) {
+	return len(encoded) > MaxLength-MaxExtensionLength-HashLength-1 && truncatedRegexp.MatchString(encoded)
+}
@@ -0,0 +1,50 @@
+package names
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	long := strings.Repeat("a", 300) + ".pdf"
+
+	type Test struct {
+		Name      string
+		Remote    string
+		Truncated bool
+	}
+	tests := []Test{
+		{Name: "Plain", Remote: "report.pdf"},
+		{Name: "Slash", Remote: "2024/01 report.pdf"},
+		{Name: "Dot", Remote: "."},
+		{Name: "Spaces", Remote: "  report  "},
+		{Name: "Percent", Remote: "100%25 done"},
+		{Name: "Long", Remote: long, Truncated: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			encoded := Encode(test.Remote)
+			assertions.LessOrEqual(len(encoded), MaxLength, "length")
+			assertions.NotContains(encoded, "/", "slash")
+			assertions.Equal(test.Truncated, IsTruncated(encoded), "truncated")
+			assertions.Equal(test.Remote, Decode(encoded), "decoded")
+		})
+	}
+}
+
+func TestDecodeDropped(t *testing.T) {
+	assertions := assert.New(t)
+
+	first := Encode(strings.Repeat("a", 300))
+	for index := range 3 * MaxTableEntries {
+		Encode(strings.Repeat("b", 300) + strconv.Itoa(index))
+	}
+	assertions.LessOrEqual(table.Len(), 2*MaxTableEntries, "table length")
+	// Only a listing of the directory recovers it
+	assertions.NotEqual(strings.Repeat("a", 300), Decode(first), "dropped name")
+}
@@ -5,22 +5,40 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"syscall"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/changes"
+	"github.com/pluto-org-co/gsuitefs/diskcache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/byid"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	"github.com/pluto-org-co/gsuitefs/filesystem/domains"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/files"
 	"github.com/pluto-org-co/gsuitefs/filesystem/shareddrives"
+	"github.com/pluto-org-co/gsuitefs/metastore"
+	"github.com/pluto-org-co/gsuitefs/organizers"
 )
 
 const DriverName = "gsuitefs"
 
+const (
+	DefaultBlockSize       = 4 << 20
+	DefaultReadAheadBlocks = 4
+	DefaultEntryTimeout    = 10 * time.Second
+	// Persisted listings are served while refreshed for a month
+	DefaultPersistentMaxAge = 30 * 24 * time.Hour
+)
+
 type Root struct {
 	fs.Inode
 
 	logger *slog.Logger
 	config *config.Config
+	// Cache path created by the mount, removed on Close
+	temporaryPath string
 }
 
 func New(logger *slog.Logger, c *config.Config) (r *Root, err error) {
@@ -28,19 +46,98 @@ func New(logger *slog.Logger, c *config.Config) (r *Root, err error) {
 		c.Cache.Expiration = time.Minute
 		logger.Warn("Cache expiration not set", "new-value", c.Cache.Expiration)
 	}
+	if c.Cache.SweepInterval == 0 {
+		c.Cache.SweepInterval = c.Cache.Expiration
+		logger.Debug("Cache sweep interval not set", "new-value", c.Cache.SweepInterval)
+	}
+	cache.SetDefaults(cache.Defaults{
+		MaxEntries:         c.Cache.MaxEntries,
+		Stale:              c.Cache.Stale,
+		NegativeExpiration: c.Cache.NegativeExpiration,
+	})
+	if c.Timeouts.Default.Entry == 0 {
+		c.Timeouts.Default.Entry = DefaultEntryTimeout
+		logger.Debug("Entry timeout not set", "new-value", c.Timeouts.Default.Entry)
+	}
+	if c.Cache.BlockSize == 0 {
+		c.Cache.BlockSize = DefaultBlockSize
+		logger.Debug("Cache block size not set", "new-value", c.Cache.BlockSize)
+	}
+	if c.Cache.ReadAheadBlocks == 0 {
+		c.Cache.ReadAheadBlocks = DefaultReadAheadBlocks
+		logger.Debug("Cache read ahead not set", "new-value", c.Cache.ReadAheadBlocks)
+	}
+	var temporaryPath string
 	if c.Cache.Path == "" {
 		c.Cache.Path, err = os.MkdirTemp("", "gsuitefs-*")
 		logger.Warn("Cache path not set", "new-value", c.Cache.Path)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize cache path: %w", err)
 		}
+		temporaryPath = c.Cache.Path
+	}
+
+	c.CacheManager, err = diskcache.New(logger, diskcache.Config{
+		Path:         c.Cache.Path,
+		MaxSize:      c.Cache.MaxSize,
+		MinFreeSpace: c.Cache.MinFreeSpace,
+		Sidecars:     []string{files.RecordSuffix, files.BlocksSuffix, files.BlockMapSuffix},
+		Temporaries:  []string{files.PartialSuffix},
+		Ignored:      []string{metastore.DirName},
+	})
+	if err != nil {
+		if temporaryPath != "" {
+			os.RemoveAll(temporaryPath)
+		}
+		return nil, fmt.Errorf("failed to initialize cache manager: %w", err)
+	}
+
+	if c.Cache.Persistent {
+		logger.Debug("Opening metadata store")
+		if c.Cache.PersistentMaxAge == 0 {
+			c.Cache.PersistentMaxAge = DefaultPersistentMaxAge
+			logger.Debug("Persistent cache max age not set", "new-value", c.Cache.PersistentMaxAge)
+		}
+		c.MetadataStore, err = metastore.Open(path.Join(c.Cache.Path, metastore.DirName), c.Cache.PersistentMaxAge)
+		if err != nil {
+			if temporaryPath != "" {
+				os.RemoveAll(temporaryPath)
+			}
+			return nil, fmt.Errorf("failed to open metadata store: %w", err)
+		}
+	}
+	if c.Include.SharedDrives != nil && c.Include.SharedDrives.DomainAdminAccess {
+		logger.Debug("Resolving shared drive organizers")
+		c.Organizers = organizers.New(logger, c.AdministratorSubject, organizers.ClientFunc(c.HttpClientProviderFunc), c.Cache.Expiration)
+	}
+	if c.Cache.ChangesInterval > 0 {
+		logger.Debug("Watching Drive changes", "interval", c.Cache.ChangesInterval)
+		c.Changes = changes.New(logger, changes.ClientFunc(c.HttpClientProviderFunc), c.SharedDriveSubject, c.Cache.ChangesInterval, c.MetadataStore)
 	}
 	return &Root{
-		logger: logger.With("context", DriverName, "inode", "root"),
-		config: c,
+		logger:        logger.With("context", DriverName, "inode", "root"),
+		config:        c,
+		temporaryPath: temporaryPath,
 	}, nil
 }
 
+// Close stops the background work and removes the cache path when it was
+// created by the mount
+func (r *Root) Close() (err error) {
+	r.config.Changes.Close()
+
+	if r.temporaryPath == "" {
+		return nil
+	}
+
+	r.logger.Debug("Removing temporary cache", "path", r.temporaryPath)
+	err = os.RemoveAll(r.temporaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to remove temporary cache: %w", err)
+	}
+	return nil
+}
+
 var (
 	_ fs.NodeOnAdder = (*Root)(nil)
 )
@@ -62,4 +159,9 @@ func (r *Root) OnAdd(ctx context.Context) {
 	} else {
 		logger.Debug("Ignoring shared-drives")
 	}
+
+	// Always present, shortcuts to files outside their drive point here
+	logger.Debug("Including files by ID")
+	node := r.NewPersistentInode(ctx, byid.New(r.logger, r.config), fs.StableAttr{Mode: syscall.S_IFDIR})
+	r.AddChild(byid.NodeName, node, false)
 }
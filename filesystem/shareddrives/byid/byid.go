@@ -0,0 +1,129 @@
+package byid
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/shareddrives/shareddrive"
+	"github.com/pluto-org-co/gsuitefs/httputils"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const NodeName = ".by-id"
+
+const ReaddirCacheKey = 0
+
+// ById lists every shared drive by its immutable ID. Hidden drives are always
+// reachable by ID even when not listed
+type ById struct {
+	fs.Inode
+
+	readdirCache cache.Cache[int, []fuse.DirEntry]
+	lookupCache  cache.Cache[string, *drive.Drive]
+	logger       *slog.Logger
+	config       *config.Config
+}
+
+func New(logger *slog.Logger, c *config.Config) (b *ById) {
+	b = &ById{logger: logger.With("inode", NodeName), config: c}
+	b.lookupCache.Persist(c.MetadataStore, "shared-drives/by-id/lookup")
+	b.readdirCache.Persist(c.MetadataStore, "shared-drives/by-id/readdir")
+	return b
+}
+
+var (
+	_ fs.NodeReaddirer = (*ById)(nil)
+	_ fs.NodeLookuper  = (*ById)(nil)
+)
+
+func (b *ById) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
+	logger := b.logger.With("action", "Readdir")
+
+	logger.Debug("Loading shared drive list")
+	dirEntries, err := b.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, b.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
+		client := b.config.HttpClientProviderFunc(ctx, b.config.AdministratorSubject)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Pulling Shared Drives ID list")
+		dirEntries = make([]fuse.DirEntry, 0, 100)
+		err = shareddrive.ListCall(driveSvc, b.config, "").
+			Context(ctx).
+			Pages(ctx, func(dl *drive.DriveList) (err error) {
+				for _, sharedDrive := range dl.Drives {
+					logger.Debug("Listing shared drive", "drive-id", sharedDrive.Id)
+					b.lookupCache.Store(sharedDrive.Id, sharedDrive, b.config.Cache.Expiration)
+					dirEntries = append(dirEntries, fuse.DirEntry{
+						Mode: syscall.S_IFDIR,
+						Name: sharedDrive.Id,
+						Ino:  inodes.Ino(inodes.KindSharedDrive, sharedDrive.Id),
+					})
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve shared drives", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	ds = fs.NewListDirStream(dirEntries)
+	return ds, fs.OK
+}
+
+func (b *ById) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
+	logger := b.logger.With("action", "Lookup", "name", name)
+
+	logger.Debug("Loading shared drive")
+	driveEntry, err := b.lookupCache.LoadOrFetch(ctx, name, b.config.Cache.Expiration, func(ctx context.Context) (driveEntry *drive.Drive, err error) {
+		client := b.config.HttpClientProviderFunc(ctx, b.config.AdministratorSubject)
+
+		logger.Debug("Preparing drive service")
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, err
+		}
+
+		driveEntry, err = driveSvc.Drives.
+			Get(name).
+			UseDomainAdminAccess(b.config.Include.SharedDrives.DomainAdminAccess).
+			Context(ctx).
+			Do()
+		if err != nil {
+			if httputils.IsNotFound(err) {
+				return nil, cache.Negative(syscall.ENOENT)
+			}
+			return nil, err
+		}
+		return driveEntry, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve shared drive", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	b.config.Timeouts.Folders.EntryOut(out)
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindSharedDrive, driveEntry.Id)}
+	if node = inodes.Reuse(&b.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
+	}
+
+	node = b.NewInode(ctx, shareddrive.New(b.logger, b.config, driveEntry), attr)
+	return node, fs.OK
+}
@@ -0,0 +1,75 @@
+package shareddrives
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/directory"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/filesystem/shareddrives/byid"
+	"google.golang.org/api/drive/v3"
+)
+
+// Shared drive listed with its name
+type entry struct {
+	name  string
+	drive *drive.Drive
+}
+
+var disambiguatedRegexp = regexp.MustCompile(`^(.*) \([A-Za-z0-9_-]+\)$`)
+
+// Appends the ID suffix to the name: Finance -> Finance (AbCdEfG)
+func disambiguate(name string, id string) (disambiguated string) {
+	suffix := id
+	if len(suffix) > directory.IdSuffixLength {
+		suffix = suffix[len(suffix)-directory.IdSuffixLength:]
+	}
+	return name + " (" + suffix + ")"
+}
+
+// Drive names that may be listed under the entry name, ordered from the most
+// to the least specific. A drive whose name already has a suffix may be
+// disambiguated again, so every suffix is stripped
+func remoteCandidates(name string) (candidates []string) {
+	name = names.Decode(name)
+	candidates = []string{name}
+	for {
+		match := disambiguatedRegexp.FindStringSubmatch(name)
+		if match == nil || match[1] == "" {
+			return candidates
+		}
+		name = match[1]
+		candidates = append(candidates, name)
+	}
+}
+
+// Computes the entries of the drives. The oldest drive keeps its name and the
+// rest are disambiguated with their ID suffix, so names don't depend on the
+// listing order. Names are claimed globally, so a generated name never matches
+// the name of another drive, and byid.NodeName is reserved. Every drive able
+// to produce a name must be passed for it to be correct, which
+// remoteCandidates guarantees
+func groupEntries(drives []*drive.Drive) (entries []entry) {
+	sorted := slices.Clone(drives)
+	slices.SortFunc(sorted, func(a, b *drive.Drive) int {
+		if c := strings.Compare(a.CreatedTime, b.CreatedTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	claimed := map[string]struct{}{byid.NodeName: {}}
+	for _, sharedDrive := range sorted {
+		name := names.Encode(sharedDrive.Name)
+		if _, found := claimed[name]; found {
+			name = names.Encode(disambiguate(sharedDrive.Name, sharedDrive.Id))
+			if _, found := claimed[name]; found {
+				continue
+			}
+		}
+		claimed[name] = struct{}{}
+		entries = append(entries, entry{name: name, drive: sharedDrive})
+	}
+	return entries
+}
@@ -0,0 +1,93 @@
+package shareddrives
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+func sharedDrive(id, name, createdTime string) (d *drive.Drive) {
+	return &drive.Drive{Id: id, Name: name, CreatedTime: createdTime}
+}
+
+func TestGroupEntries(t *testing.T) {
+	type Test struct {
+		Name   string
+		Drives []*drive.Drive
+		// Entry name and ID of the drive it resolves to
+		Listed map[string]string
+	}
+	tests := []Test{
+		{
+			Name: "Same name",
+			Drives: []*drive.Drive{
+				sharedDrive("newer000000", "Finance", "2024-02-01T00:00:00Z"),
+				sharedDrive("older000000", "Finance", "2024-01-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				"Finance":           "older000000",
+				"Finance (r000000)": "newer000000",
+			},
+		},
+		{
+			Name: "Name colliding with suffix",
+			Drives: []*drive.Drive{
+				sharedDrive("plain000000", "Finance", "2024-01-01T00:00:00Z"),
+				sharedDrive("newer000000", "Finance", "2024-03-01T00:00:00Z"),
+				sharedDrive("literal0000", "Finance (r000000)", "2024-02-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				"Finance":           "plain000000",
+				"Finance (r000000)": "literal0000",
+			},
+		},
+		{
+			Name: "Reserved name",
+			Drives: []*drive.Drive{
+				sharedDrive("byid0000000", ".by-id", "2024-01-01T00:00:00Z"),
+			},
+			Listed: map[string]string{
+				".by-id (0000000)": "byid0000000",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			listed := map[string]string{}
+			for _, e := range groupEntries(test.Drives) {
+				listed[e.name] = e.drive.Id
+			}
+			assertions.Equal(test.Listed, listed, "listed")
+
+			// Lookups only see the drives named after the candidates
+			for name, id := range test.Listed {
+				var drives []*drive.Drive
+				for _, candidate := range remoteCandidates(name) {
+					for _, d := range test.Drives {
+						if d.Name == candidate {
+							drives = append(drives, d)
+						}
+					}
+				}
+				found := false
+				for _, e := range groupEntries(drives) {
+					if e.name == name {
+						found = true
+						assertions.Equal(id, e.drive.Id, "lookup %s", name)
+					}
+				}
+				assertions.True(found, "lookup %s found", name)
+			}
+		})
+	}
+}
+
+func TestRemoteCandidates(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.Equal([]string{"Finance"}, remoteCandidates("Finance"), "plain")
+	assertions.Equal([]string{"Finance (abc1234) (def5678)", "Finance (abc1234)", "Finance"}, remoteCandidates("Finance (abc1234) (def5678)"), "nested")
+}
@@ -36,6 +36,27 @@ func New(logger *slog.Logger, c *config.Config, driveEntry *drive.Drive) (d *Dri
 
 var _ fs.NodeOnAdder = (*Drive)(nil)
 
+// ListCall prepares the list of shared drives matching the query, every drive
+// of the organization with domain admin access. Hidden drives are listed
+// unless excluded
+func ListCall(svc *drive.Service, c *config.Config, query string) (call *drive.DrivesListCall) {
+	if c.Include.SharedDrives.ExcludeHidden {
+		if query != "" {
+			query += " and "
+		}
+		query += "hidden = false"
+	}
+
+	call = svc.Drives.
+		List().
+		UseDomainAdminAccess(c.Include.SharedDrives.DomainAdminAccess).
+		PageSize(100)
+	if query != "" {
+		call = call.Q(query)
+	}
+	return call
+}
+
 func (d *Drive) OnAdd(ctx context.Context) {
 	logger := d.logger.With("action", "OnAdd")
 	if d.config.Include.SharedDrives.Active {
@@ -0,0 +1,66 @@
+package shareddrive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestListCall(t *testing.T) {
+	type Test struct {
+		Name              string
+		Query             string
+		ExcludeHidden     bool
+		DomainAdminAccess bool
+		Expected          url.Values
+	}
+	tests := []Test{
+		{Name: "Default", Expected: url.Values{"useDomainAdminAccess": {"false"}}},
+		{Name: "Query", Query: "name = 'Finance'", Expected: url.Values{"useDomainAdminAccess": {"false"}, "q": {"name = 'Finance'"}}},
+		{Name: "Domain admin access", DomainAdminAccess: true, Expected: url.Values{"useDomainAdminAccess": {"true"}}},
+		{Name: "Exclude hidden", ExcludeHidden: true, Expected: url.Values{"useDomainAdminAccess": {"false"}, "q": {"hidden = false"}}},
+		{Name: "Exclude hidden with query", Query: "name = 'Finance'", ExcludeHidden: true, Expected: url.Values{"useDomainAdminAccess": {"false"}, "q": {"name = 'Finance' and hidden = false"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			var query url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"drives": []}`))
+			}))
+			defer server.Close()
+
+			svc, err := drive.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL))
+			if !assertions.Nil(err, "failed to prepare drive service") {
+				return
+			}
+
+			c := config.Config{Include: config.Include{SharedDrives: &config.IncludeSharedDrives{
+				ExcludeHidden:     test.ExcludeHidden,
+				DomainAdminAccess: test.DomainAdminAccess,
+			}}}
+			_, err = ListCall(svc, &c, test.Query).Do()
+			if !assertions.Nil(err, "failed to list drives") {
+				return
+			}
+
+			assertions.Equal("100", query.Get("pageSize"), "page size")
+			for key, values := range test.Expected {
+				assertions.Equal(values, query[key], key)
+			}
+			if _, found := test.Expected["q"]; !found {
+				assertions.NotContains(query, "q", "query")
+			}
+		})
+	}
+}
@@ -2,8 +2,6 @@ package shareddrives
 
 import (
 	"context"
-	"errors"
-	"io"
 	"log/slog"
 	"syscall"
 
@@ -11,7 +9,12 @@ import (
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/pluto-org-co/gsuitefs/cache"
 	"github.com/pluto-org-co/gsuitefs/filesystem/config"
+	"github.com/pluto-org-co/gsuitefs/filesystem/domains/driveutils/directory"
+	"github.com/pluto-org-co/gsuitefs/filesystem/inodes"
+	"github.com/pluto-org-co/gsuitefs/filesystem/names"
+	"github.com/pluto-org-co/gsuitefs/filesystem/shareddrives/byid"
 	"github.com/pluto-org-co/gsuitefs/filesystem/shareddrives/shareddrive"
+	"github.com/pluto-org-co/gsuitefs/httputils"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 )
@@ -30,54 +33,80 @@ type SharedDrives struct {
 }
 
 func New(logger *slog.Logger, c *config.Config) (d *SharedDrives) {
-	return &SharedDrives{logger: logger.With("inode", NodeName), config: c}
+	d = &SharedDrives{logger: logger.With("inode", NodeName), config: c}
+	d.lookupCache.Persist(c.MetadataStore, "shared-drives/lookup")
+	d.readdirCache.Persist(c.MetadataStore, "shared-drives/readdir")
+	return d
 }
 
 var (
+	_ fs.NodeOnAdder   = (*SharedDrives)(nil)
 	_ fs.NodeReaddirer = (*SharedDrives)(nil)
 	_ fs.NodeLookuper  = (*SharedDrives)(nil)
 )
 
+func (s *SharedDrives) OnAdd(ctx context.Context) {
+	logger := s.logger.With("action", "OnAdd")
+
+	logger.Debug("Including drives by ID")
+	node := s.NewPersistentInode(ctx, byid.New(s.logger, s.config), fs.StableAttr{Mode: syscall.S_IFDIR})
+	s.AddChild(byid.NodeName, node, false)
+}
+
+// Stores the entries of the drives in the lookup cache
+func (s *SharedDrives) storeEntries(drives []*drive.Drive) (entries []entry) {
+	entries = groupEntries(drives)
+	for _, e := range entries {
+		s.lookupCache.Store(e.name, e.drive, s.config.Cache.Expiration)
+	}
+	return entries
+}
+
 func (s *SharedDrives) Readdir(ctx context.Context) (ds fs.DirStream, errno syscall.Errno) {
 	logger := s.logger.With("action", "Readdir")
 
-	logger.Debug("Checking cache")
-	dirEntries, found := s.readdirCache.Load(ReaddirCacheKey)
-	if !found {
+	logger.Debug("Loading shared drive list")
+	dirEntries, err := s.readdirCache.LoadOrFetch(ctx, ReaddirCacheKey, s.config.Cache.Expiration, func(ctx context.Context) (dirEntries []fuse.DirEntry, err error) {
 		client := s.config.HttpClientProviderFunc(ctx, s.config.AdministratorSubject)
 
 		logger.Debug("Preparing drive service")
 		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
 		logger.Debug("Pulling Shared Drives names list")
-		dirEntries = make([]fuse.DirEntry, 0, 100)
-		err = driveSvc.Drives.
-			List().
+		var drives []*drive.Drive
+		err = shareddrive.ListCall(driveSvc, s.config, "").
 			Context(ctx).
 			Pages(ctx, func(dl *drive.DriveList) (err error) {
 				for _, sharedDrive := range dl.Drives {
 					logger.Debug("Listing shared drive", "drive-name", sharedDrive.Name)
-					s.lookupCache.Store(sharedDrive.Name, sharedDrive, s.config.Cache.Expiration)
-					dirEntries = append(dirEntries, fuse.DirEntry{
-						Mode: syscall.S_IFDIR,
-						Name: sharedDrive.Name,
-					})
+					drives = append(drives, sharedDrive)
 				}
 				return nil
 			})
 		if err != nil {
-			logger.Error("failed to retrieve shared drives", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
-		logger.Debug("Storing in cache")
-		s.readdirCache.Store(ReaddirCacheKey, dirEntries, s.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		entries := s.storeEntries(drives)
+		dirEntries = make([]fuse.DirEntry, 0, len(entries))
+		for _, e := range entries {
+			if names.Decode(e.name) != e.drive.Name {
+				logger.Debug("Disambiguating duplicated name", "drive-name", e.drive.Name, "name", e.name)
+			}
+			dirEntries = append(dirEntries, fuse.DirEntry{
+				Mode: syscall.S_IFDIR,
+				Name: e.name,
+				Ino:  inodes.Ino(inodes.KindSharedDrive, e.drive.Id),
+			})
+		}
+		return dirEntries, nil
+	})
+	if err != nil {
+		logger.Error("failed to retrieve shared drives", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
 	}
 
 	ds = fs.NewListDirStream(dirEntries)
@@ -87,45 +116,58 @@ func (s *SharedDrives) Readdir(ctx context.Context) (ds fs.DirStream, errno sysc
 func (s *SharedDrives) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (node *fs.Inode, errno syscall.Errno) {
 	logger := s.logger.With("action", "Lookup", "name", name)
 
-	logger.Debug("Checking cache")
-	driveEntry, found := s.lookupCache.Load(name)
-	if !found {
+	if name == byid.NodeName {
+		return s.GetChild(name), fs.OK
+	}
+
+	logger.Debug("Loading shared drive")
+	driveEntry, err := s.lookupCache.LoadOrFetch(ctx, name, s.config.Cache.Expiration, func(ctx context.Context) (driveEntry *drive.Drive, err error) {
 		client := s.config.HttpClientProviderFunc(ctx, s.config.AdministratorSubject)
 
 		logger.Debug("Preparing drive service")
 		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			logger.Error("failed to prepare service", "error-msg", err)
-			return nil, fs.ToErrno(err)
+			return nil, err
 		}
 
-		err = driveSvc.Drives.
-			List().
-			Context(ctx).
-			Pages(ctx, func(dl *drive.DriveList) (err error) {
-				for _, sharedDrive := range dl.Drives {
-					if sharedDrive.Name == name {
-						driveEntry = sharedDrive
-						return io.EOF
+		// Disambiguated names are only known after listing every drive able
+		// to claim the name
+		var drives []*drive.Drive
+		for _, candidate := range remoteCandidates(name) {
+			err = shareddrive.ListCall(driveSvc, s.config, "name = '"+directory.EscapeQuery(candidate)+"'").
+				Context(ctx).
+				Pages(ctx, func(dl *drive.DriveList) (err error) {
+					for _, sharedDrive := range dl.Drives {
+						if sharedDrive.Name == candidate {
+							drives = append(drives, sharedDrive)
+						}
 					}
-				}
-				return nil
-			})
-		if err != nil && !errors.Is(err, io.EOF) {
-			logger.Error("failed to retrieve shared drive", "error-msg", err)
-			return nil, fs.ToErrno(err)
+					return nil
+				})
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		if driveEntry == nil {
-			logger.Error("drive not found")
-			return nil, syscall.ENOENT
+		for _, e := range s.storeEntries(drives) {
+			if e.name == name {
+				return e.drive, nil
+			}
 		}
-		logger.Debug("Storing in cache")
-		s.lookupCache.Store(name, driveEntry, s.config.Cache.Expiration)
-	} else {
-		logger.Debug("Using cache")
+		return nil, cache.Negative(syscall.ENOENT)
+	})
+	if err != nil {
+		logger.Error("failed to retrieve shared drive", "error-msg", err, "error-reason", httputils.Reason(err))
+		return nil, httputils.ToErrno(err)
+	}
+
+	s.config.Timeouts.Folders.EntryOut(out)
+	attr := fs.StableAttr{Mode: syscall.S_IFDIR, Ino: inodes.Ino(inodes.KindSharedDrive, driveEntry.Id)}
+	if node = inodes.Reuse(&s.Inode, name, attr); node != nil {
+		logger.Debug("Reusing inode")
+		return node, fs.OK
 	}
 
-	node = s.NewInode(ctx, shareddrive.New(s.logger, s.config, driveEntry), fs.StableAttr{Mode: syscall.S_IFDIR})
+	node = s.NewInode(ctx, shareddrive.New(s.logger, s.config, driveEntry), attr)
 	return node, fs.OK
 }
@@ -0,0 +1,131 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"syscall"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// IsNotFound reports if the Google API request failed because the resource
+// doesn't exist
+func IsNotFound(err error) (ok bool) {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// Reasons of the Google API errors translated by ToErrno
+const (
+	ReasonInsufficientPermissions = "insufficientPermissions"
+	ReasonDomainPolicy            = "domainPolicy"
+	ReasonRateLimitExceeded       = "rateLimitExceeded"
+	ReasonUserRateLimitExceeded   = "userRateLimitExceeded"
+	ReasonCannotExportFile        = "cannotExportFile"
+	ReasonExportSizeLimitExceeded = "exportSizeLimitExceeded"
+)
+
+// OAuth2 errors returned when the service account lacks the delegated scopes
+// or the subject refused them
+const (
+	UnauthorizedClient = "unauthorized_client"
+	AccessDenied       = "access_denied"
+)
+
+// Reason returns the reason of the failed Google API request, the OAuth2
+// error code when the token was refused, empty for other errors
+func Reason(err error) (reason string) {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, item := range apiErr.Errors {
+			if item.Reason != "" {
+				return item.Reason
+			}
+		}
+		return ""
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return retrieveErr.ErrorCode
+	}
+	return ""
+}
+
+// ToErrno translates the errors of the Google API requests into the errno
+// reported to the file system users. Errnos and os errors are kept and
+// anything else is reported as EIO
+func ToErrno(err error) (errno syscall.Errno) {
+	if err == nil {
+		return 0
+	}
+
+	if errors.As(err, &errno) {
+		return errno
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErrno(apiErr.Code, Reason(err))
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return retrieveErrno(retrieveErr)
+	}
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, os.ErrPermission):
+		return syscall.EPERM
+	case errors.Is(err, os.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, context.Canceled):
+		return syscall.EINTR
+	case errors.Is(err, context.DeadlineExceeded):
+		return syscall.ETIMEDOUT
+	}
+	return syscall.EIO
+}
+
+func apiErrno(code int, reason string) (errno syscall.Errno) {
+	switch reason {
+	case ReasonCannotExportFile, ReasonExportSizeLimitExceeded:
+		return syscall.EFBIG
+	case ReasonRateLimitExceeded, ReasonUserRateLimitExceeded:
+		return syscall.EAGAIN
+	case ReasonInsufficientPermissions, ReasonDomainPolicy:
+		return syscall.EACCES
+	}
+
+	switch {
+	case code == http.StatusNotFound:
+		return syscall.ENOENT
+	case code == http.StatusTooManyRequests:
+		return syscall.EAGAIN
+	case code == http.StatusUnauthorized:
+		return syscall.EPERM
+	case code == http.StatusForbidden:
+		return syscall.EACCES
+	}
+	return syscall.EIO
+}
+
+// Only the refused delegations are permission errors, the failures of the
+// token endpoint are retried like the ones of the API
+func retrieveErrno(err *oauth2.RetrieveError) (errno syscall.Errno) {
+	switch err.ErrorCode {
+	case UnauthorizedClient, AccessDenied:
+		// Missing domain-wide delegation or scopes for the subject
+		return syscall.EPERM
+	}
+
+	if err.Response != nil && (err.Response.StatusCode == http.StatusTooManyRequests || err.Response.StatusCode >= http.StatusInternalServerError) {
+		return syscall.EAGAIN
+	}
+	return syscall.EIO
+}
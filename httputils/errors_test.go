@@ -0,0 +1,73 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+func apiError(code int, reason string) (err *googleapi.Error) {
+	err = &googleapi.Error{Code: code}
+	if reason != "" {
+		err.Errors = []googleapi.ErrorItem{{Reason: reason}}
+	}
+	return err
+}
+
+func retrieveError(statusCode int, errorCode string) (err *oauth2.RetrieveError) {
+	return &oauth2.RetrieveError{
+		Response:  &http.Response{StatusCode: statusCode},
+		ErrorCode: errorCode,
+	}
+}
+
+func TestToErrno(t *testing.T) {
+	type Test struct {
+		Name   string
+		Err    error
+		Errno  syscall.Errno
+		Reason string
+	}
+	tests := []Test{
+		{Name: "Nil", Err: nil, Errno: 0},
+		{Name: "Not found", Err: apiError(http.StatusNotFound, "notFound"), Errno: syscall.ENOENT, Reason: "notFound"},
+		{Name: "Insufficient permissions", Err: apiError(http.StatusForbidden, ReasonInsufficientPermissions), Errno: syscall.EACCES, Reason: ReasonInsufficientPermissions},
+		{Name: "Domain policy", Err: apiError(http.StatusForbidden, ReasonDomainPolicy), Errno: syscall.EACCES, Reason: ReasonDomainPolicy},
+		{Name: "Forbidden", Err: apiError(http.StatusForbidden, ""), Errno: syscall.EACCES},
+		{Name: "Rate limit", Err: apiError(http.StatusForbidden, ReasonRateLimitExceeded), Errno: syscall.EAGAIN, Reason: ReasonRateLimitExceeded},
+		{Name: "User rate limit", Err: apiError(http.StatusForbidden, ReasonUserRateLimitExceeded), Errno: syscall.EAGAIN, Reason: ReasonUserRateLimitExceeded},
+		{Name: "Too many requests", Err: apiError(http.StatusTooManyRequests, ""), Errno: syscall.EAGAIN},
+		{Name: "Unauthorized", Err: apiError(http.StatusUnauthorized, ""), Errno: syscall.EPERM},
+		{Name: "Internal error", Err: apiError(http.StatusInternalServerError, "backendError"), Errno: syscall.EIO, Reason: "backendError"},
+		{Name: "Unavailable", Err: apiError(http.StatusServiceUnavailable, ""), Errno: syscall.EIO},
+		{Name: "Cannot export", Err: apiError(http.StatusForbidden, ReasonCannotExportFile), Errno: syscall.EFBIG, Reason: ReasonCannotExportFile},
+		{Name: "Export size limit", Err: apiError(http.StatusForbidden, ReasonExportSizeLimitExceeded), Errno: syscall.EFBIG, Reason: ReasonExportSizeLimitExceeded},
+		{Name: "Unauthorized client", Err: retrieveError(http.StatusUnauthorized, UnauthorizedClient), Errno: syscall.EPERM, Reason: UnauthorizedClient},
+		{Name: "Access denied", Err: retrieveError(http.StatusForbidden, AccessDenied), Errno: syscall.EPERM, Reason: AccessDenied},
+		{Name: "Token endpoint unavailable", Err: retrieveError(http.StatusServiceUnavailable, ""), Errno: syscall.EAGAIN},
+		{Name: "Invalid grant", Err: retrieveError(http.StatusBadRequest, "invalid_grant"), Errno: syscall.EIO, Reason: "invalid_grant"},
+		{Name: "Wrapped", Err: fmt.Errorf("failed to list: %w", apiError(http.StatusNotFound, "notFound")), Errno: syscall.ENOENT, Reason: "notFound"},
+		{Name: "Wrapped token error", Err: fmt.Errorf("failed to list: %w", retrieveError(http.StatusUnauthorized, UnauthorizedClient)), Errno: syscall.EPERM, Reason: UnauthorizedClient},
+		{Name: "Errno", Err: fmt.Errorf("failed to open: %w", syscall.ENOTDIR), Errno: syscall.ENOTDIR},
+		{Name: "Missing file", Err: fmt.Errorf("failed to open: %w", os.ErrNotExist), Errno: syscall.ENOENT},
+		{Name: "Canceled", Err: context.Canceled, Errno: syscall.EINTR},
+		{Name: "Deadline", Err: context.DeadlineExceeded, Errno: syscall.ETIMEDOUT},
+		{Name: "Other", Err: errors.New("connection reset"), Errno: syscall.EIO},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			assertions.Equal(test.Errno, ToErrno(test.Err), "errno")
+			assertions.Equal(test.Reason, Reason(test.Err), "reason")
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package boundedmap provides a map keeping a bounded number of entries.
+//
+// Entries are kept in two generations. When the current generation reaches
+// the limit it replaces the previous one, dropping its entries, and the
+// entries of the previous generation are moved back to the current one when
+// used. Memory is bounded by twice the limit without tracking every access
+package boundedmap
+
+import "sync"
+
+type Map[K comparable, V any] struct {
+	mu       sync.Mutex
+	limit    int
+	current  map[K]V
+	previous map[K]V
+}
+
+func New[K comparable, V any](limit int) (m *Map[K, V]) {
+	return &Map[K, V]{
+		limit:    limit,
+		current:  make(map[K]V),
+		previous: make(map[K]V),
+	}
+}
+
+func (m *Map[K, V]) load(key K) (value V, found bool) {
+	value, found = m.current[key]
+	if found {
+		return value, true
+	}
+	value, found = m.previous[key]
+	if found {
+		delete(m.previous, key)
+		m.store(key, value)
+	}
+	return value, found
+}
+
+func (m *Map[K, V]) store(key K, value V) {
+	if _, found := m.current[key]; !found && len(m.current) >= m.limit {
+		m.previous = m.current
+		m.current = make(map[K]V)
+	}
+	delete(m.previous, key)
+	m.current[key] = value
+}
+
+// Load returns the value of the key, found is false when missing or dropped
+func (m *Map[K, V]) Load(key K) (value V, found bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.load(key)
+}
+
+func (m *Map[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store(key, value)
+}
+
+// Swap stores the value returning the previous one, loaded is false when
+// missing or dropped
+func (m *Map[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, loaded = m.load(key)
+	m.store(key, value)
+	return previous, loaded
+}
+
+// Len returns the number of entries kept, at most twice the limit
+func (m *Map[K, V]) Len() (length int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.current) + len(m.previous)
+}
@@ -0,0 +1,49 @@
+package boundedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	t.Run("Bounded", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		m := New[int, int](10)
+		for key := range 1_000 {
+			m.Store(key, key)
+		}
+		assertions.LessOrEqual(m.Len(), 20, "length")
+
+		_, found := m.Load(0)
+		assertions.False(found, "oldest dropped")
+		value, found := m.Load(999)
+		assertions.True(found, "newest kept")
+		assertions.Equal(999, value, "value")
+	})
+	t.Run("Used entries kept", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		m := New[int, int](10)
+		m.Store(-1, -1)
+		for key := range 1_000 {
+			m.Store(key, key)
+			_, found := m.Load(-1)
+			if !assertions.True(found, "used entry dropped at %d", key) {
+				return
+			}
+		}
+	})
+	t.Run("Swap", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		m := New[string, int](10)
+		_, loaded := m.Swap("key", 1)
+		assertions.False(loaded, "loaded")
+
+		previous, loaded := m.Swap("key", 2)
+		assertions.True(loaded, "loaded")
+		assertions.Equal(1, previous, "previous")
+	})
+}
@@ -0,0 +1,245 @@
+package metastore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory created under the cache path holding the records
+const DirName = "metadata"
+
+// Record stored for every key
+type Record struct {
+	Key       string          `json:"key"`
+	FetchedAt time.Time       `json:"fetched-at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Store persists the fetched metadata between mounts. Every key is stored
+// as a JSON file named after its hash, sharded by the first byte. A nil
+// Store keeps nothing
+type Store struct {
+	path string
+	// Records fetched before are missing and removed by Prune, none when
+	// zero
+	maxAge time.Duration
+}
+
+// Open creates the store. Expired records are still served while refreshed,
+// maxAge bounds how long they are kept and is unrelated to the expiration
+func Open(path string, maxAge time.Duration) (s *Store, err error) {
+	err = os.MkdirAll(path, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+	return &Store{path: path, maxAge: maxAge}, nil
+}
+
+func (s *Store) filename(key string) (filename string) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.path, name[:2], name+".json")
+}
+
+// Get decodes the value of the key into value. Unreadable records and
+// records older than maxAge are reported as missing
+func (s *Store) Get(key string, value any) (fetchedAt time.Time, found bool, err error) {
+	if s == nil {
+		return fetchedAt, false, nil
+	}
+
+	contents, err := os.ReadFile(s.filename(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fetchedAt, false, nil
+		}
+		return fetchedAt, false, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	var record Record
+	err = json.Unmarshal(contents, &record)
+	if err != nil || record.Key != key {
+		return fetchedAt, false, nil
+	}
+
+	if s.maxAge > 0 && time.Since(record.FetchedAt) > s.maxAge {
+		// Not pruned yet
+		return fetchedAt, false, nil
+	}
+
+	err = json.Unmarshal(record.Value, value)
+	if err != nil {
+		return fetchedAt, false, nil
+	}
+	return record.FetchedAt, true, nil
+}
+
+// Put stores the value of the key fetched at the time
+func (s *Store) Put(key string, value any, fetchedAt time.Time) (err error) {
+	if s == nil {
+		return nil
+	}
+
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	contents, err := json.Marshal(Record{Key: key, FetchedAt: fetchedAt, Value: rawValue})
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	filename := s.filename(key)
+	err = os.MkdirAll(filepath.Dir(filename), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create shard: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create record: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(contents)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close record: %w", err)
+	}
+
+	// Pruned by the age of the value, not the time it was written
+	err = os.Chtimes(file.Name(), fetchedAt, fetchedAt)
+	if err != nil {
+		return fmt.Errorf("failed to change record times: %w", err)
+	}
+
+	// Replaced atomically so readers never see a partial record
+	err = os.Rename(file.Name(), filename)
+	if err != nil {
+		return fmt.Errorf("failed to move record into place: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the record of the key
+func (s *Store) Delete(key string) (err error) {
+	if s == nil {
+		return nil
+	}
+
+	err = os.Remove(s.filename(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove record: %w", err)
+	}
+	return nil
+}
+
+// Purge removes the records of every key of the namespace. Keys are hashed in
+// the file names, so every record is read
+func (s *Store) Purge(namespace string) (err error) {
+	if s == nil {
+		return nil
+	}
+
+	prefix := namespace + "/"
+	return filepath.WalkDir(s.path, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(filename) != ".json" {
+			return nil
+		}
+
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to read record: %w", err)
+		}
+
+		var record struct {
+			Key string `json:"key"`
+		}
+		if json.Unmarshal(contents, &record) != nil || !strings.HasPrefix(record.Key, prefix) {
+			return nil
+		}
+
+		err = os.Remove(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove record: %w", err)
+		}
+		return nil
+	})
+}
+
+// Prune removes the records fetched more than maxAge ago, and the temporary
+// files left behind by interrupted writes
+func (s *Store) Prune() (err error) {
+	if s == nil || s.maxAge <= 0 {
+		return nil
+	}
+
+	oldest := time.Now().Add(-s.maxAge)
+	return filepath.WalkDir(s.path, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to retrieve record info: %w", err)
+		}
+		if !info.ModTime().Before(oldest) {
+			return nil
+		}
+
+		err = os.Remove(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove record: %w", err)
+		}
+		return nil
+	})
+}
+
+// Sweep prunes the store right away and then every interval until the
+// context is done
+func (s *Store) Sweep(ctx context.Context, interval time.Duration) {
+	if s == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Prune()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package metastore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Prune(t *testing.T) {
+	assertions := assert.New(t)
+
+	s, err := Open(t.TempDir(), time.Hour)
+	if !assertions.Nil(err, "failed to open store") {
+		return
+	}
+	err = s.Put("expired", "value", time.Now().Add(-2*time.Hour))
+	if !assertions.Nil(err, "failed to put expired") {
+		return
+	}
+	err = s.Put("fresh", "value", time.Now())
+	if !assertions.Nil(err, "failed to put fresh") {
+		return
+	}
+
+	var value string
+	_, found, err := s.Get("expired", &value)
+	assertions.Nil(err, "failed to get expired")
+	assertions.False(found, "expired before the prune")
+
+	err = s.Prune()
+	if !assertions.Nil(err, "failed to prune") {
+		return
+	}
+
+	_, err = os.Stat(s.filename("expired"))
+	assertions.ErrorIs(err, os.ErrNotExist, "expired pruned")
+	_, found, err = s.Get("fresh", &value)
+	assertions.Nil(err, "failed to get fresh")
+	assertions.True(found, "fresh kept")
+	assertions.Equal("value", value, "value")
+}
+
+func TestStore_Purge(t *testing.T) {
+	assertions := assert.New(t)
+
+	s, err := Open(t.TempDir(), 0)
+	if !assertions.Nil(err, "failed to open store") {
+		return
+	}
+	for _, key := range []string{"purged/a", "purged/b", "purged-not/a", "kept/a"} {
+		err = s.Put(key, key, time.Now())
+		if !assertions.Nil(err, "failed to put %s", key) {
+			return
+		}
+	}
+
+	err = s.Purge("purged")
+	if !assertions.Nil(err, "failed to purge") {
+		return
+	}
+
+	for key, expected := range map[string]bool{"purged/a": false, "purged/b": false, "purged-not/a": true, "kept/a": true} {
+		var value string
+		_, found, err := s.Get(key, &value)
+		assertions.Nil(err, "failed to get %s", key)
+		assertions.Equal(expected, found, "found %s", key)
+	}
+}
+
+func TestStore_Nil(t *testing.T) {
+	assertions := assert.New(t)
+
+	var s *Store
+	assertions.Nil(s.Put("key", "value", time.Now()), "put")
+	_, found, err := s.Get("key", new(string))
+	assertions.Nil(err, "get")
+	assertions.False(found, "found")
+	assertions.Nil(s.Prune(), "prune")
+}
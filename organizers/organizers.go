@@ -0,0 +1,114 @@
+package organizers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pluto-org-co/gsuitefs/cache"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const (
+	RoleOrganizer = "organizer"
+	TypeUser      = "user"
+)
+
+// Fields of the permissions required to find the members
+const PermissionFields = "nextPageToken,permissions(type,role,emailAddress,deleted)"
+
+type ClientFunc func(ctx context.Context, subject string) (client *http.Client)
+
+// Resolver finds the user impersonated to traverse every shared drive. Domain
+// admin access lists every drive of the organization but only members can
+// list their files, so the drives without the administrator are traversed as
+// one of their organizers
+type Resolver struct {
+	logger     *slog.Logger
+	admin      string
+	client     ClientFunc
+	expiration time.Duration
+	subjects   cache.Cache[string, string]
+}
+
+func New(logger *slog.Logger, admin string, client ClientFunc, expiration time.Duration) (r *Resolver) {
+	return &Resolver{
+		logger:     logger.With("context", "organizers"),
+		admin:      admin,
+		client:     client,
+		expiration: expiration,
+	}
+}
+
+// Subject returns the administrator when it is a member of the drive, else an
+// organizer or any other user member. Falls back to the administrator when
+// no member is found
+func (r *Resolver) Subject(ctx context.Context, driveId string) (subject string) {
+	logger := r.logger.With("action", "Subject", "drive-id", driveId)
+
+	subject, err := r.subjects.LoadOrFetch(ctx, driveId, r.expiration, func(ctx context.Context) (subject string, err error) {
+		return r.find(ctx, logger, driveId)
+	})
+	if err != nil {
+		logger.Warn("Failed to find drive member, using administrator", "error-msg", err)
+		return r.admin
+	}
+	return subject
+}
+
+func (r *Resolver) find(ctx context.Context, logger *slog.Logger, driveId string) (subject string, err error) {
+	client := r.client(ctx, r.admin)
+
+	logger.Debug("Preparing drive service")
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debug("Pulling drive permissions")
+	var organizer, member string
+	err = driveSvc.Permissions.
+		List(driveId).
+		Fields(PermissionFields).
+		SupportsAllDrives(true).
+		UseDomainAdminAccess(true).
+		PageSize(100).
+		Context(ctx).
+		Pages(ctx, func(pl *drive.PermissionList) (err error) {
+			for _, permission := range pl.Permissions {
+				if permission.Type != TypeUser || permission.Deleted || permission.EmailAddress == "" {
+					continue
+				}
+				if strings.EqualFold(permission.EmailAddress, r.admin) {
+					organizer = r.admin
+					return io.EOF
+				}
+				if permission.Role == RoleOrganizer && organizer == "" {
+					organizer = permission.EmailAddress
+				}
+				if member == "" {
+					member = permission.EmailAddress
+				}
+			}
+			return nil
+		})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	switch {
+	case organizer != "":
+		subject = organizer
+	case member != "":
+		subject = member
+	default:
+		subject = r.admin
+	}
+	logger.Debug("Drive member found", "subject", subject)
+	return subject, nil
+}
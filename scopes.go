@@ -1,6 +1,7 @@
 package gsuitefs
 
 import (
+	"github.com/pluto-org-co/gsuitefs/filesystem/config"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/gmail/v1"
@@ -17,3 +18,19 @@ var Scopes = []string{
 	drive.DriveScope,
 	gmail.GmailReadonlyScope,
 }
+
+// API Permissions only required when groups are included
+var GroupScopes = []string{
+	admin.AdminDirectoryGroupReadonlyScope,
+	admin.AdminDirectoryGroupMemberReadonlyScope,
+}
+
+// Returns the scopes required by the included features. Every scope
+// returned must be granted to the service account through DWD
+func ScopesFor(include *config.Include) (scopes []string) {
+	scopes = append(scopes, Scopes...)
+	if include.Domains != nil && include.Domains.Groups != nil {
+		scopes = append(scopes, GroupScopes...)
+	}
+	return scopes
+}